@@ -0,0 +1,267 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// baggage header keys
+const (
+	otBaggagePrefix  = "ot-baggage-"
+	w3cBaggageHeader = "baggage"
+)
+
+var _ model.BaggageFields = (*baggage)(nil)
+
+// baggage holds the OpenTracing baggage items of a SpanContext. It implements
+// model.BaggageFields so it travels with the zipkin-go SpanContext and gets
+// inherited by child spans, but it is immutable: every mutation through the
+// model.BaggageFields interface is rejected and SetBaggageItem derives a new
+// set using withItem.
+//
+// The W3C tracestate of an extracted trace context travels along with the
+// baggage so it can be forwarded, it is not a baggage item.
+type baggage struct {
+	items      map[string]string
+	traceState string
+}
+
+// newBaggage returns the baggage found in the provided fields. Fields set by
+// native zipkin-go instrumentation are copied, multiple values for a key are
+// joined by a comma.
+func newBaggage(fields model.BaggageFields) *baggage {
+	switch b := fields.(type) {
+	case nil:
+		return nil
+	case *baggage:
+		return b
+	}
+	items := make(map[string]string)
+	fields.Iterate(func(key string, values []string) {
+		if len(values) > 0 {
+			items[key] = strings.Join(values, ",")
+		}
+	})
+	if len(items) == 0 {
+		return nil
+	}
+	return &baggage{items: items}
+}
+
+// withItem returns a copy of the baggage with key set to value.
+func (b *baggage) withItem(key, value string) *baggage {
+	items := make(map[string]string, b.len()+1)
+	if b != nil {
+		for k, v := range b.items {
+			items[k] = v
+		}
+	}
+	items[key] = value
+	return &baggage{items: items, traceState: b.getTraceState()}
+}
+
+// merge returns the baggage holding the items of both b and other. Items and
+// the tracestate of b take precedence.
+func (b *baggage) merge(other *baggage) *baggage {
+	if other == nil || b == other {
+		return b
+	}
+	if b == nil {
+		return other
+	}
+	traceState := b.traceState
+	if traceState == "" {
+		traceState = other.traceState
+	}
+	if len(other.items) == 0 && traceState == b.traceState {
+		return b
+	}
+	items := make(map[string]string, len(b.items)+len(other.items))
+	for k, v := range other.items {
+		items[k] = v
+	}
+	for k, v := range b.items {
+		items[k] = v
+	}
+	return &baggage{items: items, traceState: traceState}
+}
+
+// withTraceState returns a copy of the baggage holding the W3C tracestate.
+func (b *baggage) withTraceState(traceState string) *baggage {
+	if traceState == b.getTraceState() {
+		return b
+	}
+	var items map[string]string
+	if b != nil {
+		items = b.items
+	}
+	return &baggage{items: items, traceState: traceState}
+}
+
+// getTraceState returns the W3C tracestate forwarded with the baggage.
+func (b *baggage) getTraceState() string {
+	if b == nil {
+		return ""
+	}
+	return b.traceState
+}
+
+// item returns the value for key or an empty string if not found.
+func (b *baggage) item(key string) string {
+	v, _ := b.lookup(key)
+	return v
+}
+
+func (b *baggage) len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.items)
+}
+
+// size returns the size of all keys and values combined.
+func (b *baggage) size() int {
+	var size int
+	b.foreach(func(k, v string) bool {
+		size += len(k) + len(v)
+		return true
+	})
+	return size
+}
+
+func (b *baggage) foreach(handler func(k, v string) bool) {
+	if b == nil {
+		return
+	}
+	for k, v := range b.items {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+// Get belongs to the model.BaggageFields interface
+func (b *baggage) Get(key string) []string {
+	if v, ok := b.lookup(key); ok {
+		return []string{v}
+	}
+	return nil
+}
+
+func (b *baggage) lookup(key string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	v, ok := b.items[key]
+	return v, ok
+}
+
+// Add belongs to the model.BaggageFields interface. Baggage is immutable so
+// it always returns false.
+func (b *baggage) Add(key string, value ...string) bool { return false }
+
+// Set belongs to the model.BaggageFields interface. Baggage is immutable so
+// it always returns false.
+func (b *baggage) Set(key string, value ...string) bool { return false }
+
+// Delete belongs to the model.BaggageFields interface. Baggage is immutable
+// so it always returns false.
+func (b *baggage) Delete(key string) bool { return false }
+
+// Iterate belongs to the model.BaggageFields interface
+func (b *baggage) Iterate(f func(key string, values []string)) {
+	b.foreach(func(k, v string) bool {
+		f(k, []string{v})
+		return true
+	})
+}
+
+// injectBaggage writes the baggage items using the provided encoding.
+func injectBaggage(b *baggage, encoding BaggageEncoding, set func(key, value string)) {
+	if b.len() == 0 {
+		return
+	}
+	if encoding == BaggageEncodingOT || encoding == BaggageEncodingBoth {
+		for k, v := range b.items {
+			set(otBaggagePrefix+k, url.QueryEscape(v))
+		}
+	}
+	if encoding == BaggageEncodingW3C || encoding == BaggageEncodingBoth {
+		set(w3cBaggageHeader, buildW3CBaggage(b))
+	}
+}
+
+// extractBaggage collects the baggage items found in a (lowercased) header
+// into items.
+func extractBaggage(key, value string, items map[string]string) {
+	switch {
+	case strings.HasPrefix(key, otBaggagePrefix):
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		items[key[len(otBaggagePrefix):]] = value
+	case strings.HasPrefix(key, jaegerBaggagePrefix):
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		items[key[len(jaegerBaggagePrefix):]] = value
+	case key == w3cBaggageHeader:
+		parseW3CBaggage(value, items)
+	}
+}
+
+// buildW3CBaggage returns the W3C baggage header value. Members are sorted by
+// key to keep the header stable.
+func buildW3CBaggage(b *baggage) string {
+	keys := make([]string, 0, len(b.items))
+	for k := range b.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	members := make([]string, 0, len(keys))
+	for _, k := range keys {
+		members = append(members, url.PathEscape(k)+"="+url.PathEscape(b.items[k]))
+	}
+	return strings.Join(members, ",")
+}
+
+// parseW3CBaggage adds the members of a W3C baggage header to items. Member
+// properties are ignored as are malformed members.
+func parseW3CBaggage(header string, items map[string]string) {
+	for _, member := range strings.Split(header, ",") {
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		kv := strings.SplitN(member, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimSpace(kv[0]))
+		if err != nil || key == "" {
+			continue
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(kv[1]))
+		if err != nil {
+			continue
+		}
+		items[key] = value
+	}
+}
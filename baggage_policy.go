@@ -0,0 +1,152 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"errors"
+	"path"
+	"sort"
+)
+
+// Baggage policy rejection reasons
+var (
+	ErrBaggageKeyNotAllowed = errors.New("baggage key not allowed")
+	ErrBaggageKeyTooLong    = errors.New("baggage key exceeds maximum size")
+	ErrBaggageValueTooLong  = errors.New("baggage value exceeds maximum size")
+	ErrBaggageTooManyItems  = errors.New("baggage exceeds maximum number of items")
+	ErrBaggageTooLarge      = errors.New("baggage exceeds maximum total size")
+	ErrBaggageItemDropped   = errors.New("baggage item dropped by filter")
+)
+
+// BaggagePolicy restricts the baggage items accepted by SetBaggageItem and
+// the baggage items read by Extract and written by Inject. Zero values disable
+// the corresponding restriction.
+type BaggagePolicy struct {
+	// AllowedKeys holds the patterns of the allowed baggage keys using the
+	// path.Match syntax, e.g. "x-tenant-*". If empty all keys are allowed.
+	AllowedKeys []string
+	// MaxItems is the maximum number of baggage items.
+	MaxItems int
+	// MaxKeySize is the maximum size of a baggage key in bytes.
+	MaxKeySize int
+	// MaxValueSize is the maximum size of a baggage value in bytes.
+	MaxValueSize int
+	// MaxTotalSize is the maximum size of all baggage keys and values
+	// combined in bytes.
+	MaxTotalSize int
+	// Filter is called for every item allowed by the other rules. It can
+	// rewrite the value or drop the item by returning false.
+	Filter func(key, value string) (string, bool)
+	// OnReject is called for every rejected item with the reason of the
+	// rejection.
+	OnReject func(key, value string, reason error)
+
+	logger *rateLimitedLogger
+}
+
+// admit checks a baggage item against the policy given the number of items
+// and the total size of the baggage it will be added to. It returns the value
+// to store or the reason of the rejection.
+func (p *BaggagePolicy) admit(key, value string, count, size int) (string, error) {
+	if !p.allowed(key) {
+		return "", ErrBaggageKeyNotAllowed
+	}
+	if p.MaxKeySize > 0 && len(key) > p.MaxKeySize {
+		return "", ErrBaggageKeyTooLong
+	}
+	if p.Filter != nil {
+		var keep bool
+		if value, keep = p.Filter(key, value); !keep {
+			return "", ErrBaggageItemDropped
+		}
+	}
+	if p.MaxValueSize > 0 && len(value) > p.MaxValueSize {
+		return "", ErrBaggageValueTooLong
+	}
+	if p.MaxItems > 0 && count+1 > p.MaxItems {
+		return "", ErrBaggageTooManyItems
+	}
+	if p.MaxTotalSize > 0 && size+len(key)+len(value) > p.MaxTotalSize {
+		return "", ErrBaggageTooLarge
+	}
+	return value, nil
+}
+
+func (p *BaggagePolicy) allowed(key string) bool {
+	if len(p.AllowedKeys) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedKeys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// admitItem checks a baggage item to be added to b.
+func (p *BaggagePolicy) admitItem(b *baggage, key, value string) (string, error) {
+	count, size := b.len(), b.size()
+	if old, ok := b.lookup(key); ok {
+		// the item replaces an existing one
+		count--
+		size -= len(key) + len(old)
+	}
+	return p.admit(key, value, count, size)
+}
+
+// apply returns the baggage holding the items admitted by the policy. Items
+// are processed in key order so limits are enforced deterministically.
+func (p *BaggagePolicy) apply(b *baggage) *baggage {
+	if b.len() == 0 {
+		return b
+	}
+	keys := make([]string, 0, len(b.items))
+	for k := range b.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		items   = make(map[string]string, len(keys))
+		size    int
+		changed bool
+	)
+	for _, k := range keys {
+		v, err := p.admit(k, b.items[k], len(items), size)
+		if err != nil {
+			p.reject(k, b.items[k], err)
+			changed = true
+			continue
+		}
+		changed = changed || v != b.items[k]
+		items[k] = v
+		size += len(k) + len(v)
+	}
+	if !changed {
+		return b
+	}
+	if len(items) == 0 && b.traceState == "" {
+		return nil
+	}
+	return &baggage{items: items, traceState: b.traceState}
+}
+
+func (p *BaggagePolicy) reject(key, value string, reason error) {
+	p.logger.warn("rejected baggage item", "key", key, "reason", reason)
+	if p.OnReject != nil {
+		p.OnReject(key, value, reason)
+	}
+}
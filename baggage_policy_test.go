@@ -0,0 +1,107 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaggagePolicy_SetBaggageItem(t *testing.T) {
+	rejected := map[string]error{}
+	policy := BaggagePolicy{
+		AllowedKeys:  []string{"tenant", "x-*"},
+		MaxItems:     2,
+		MaxKeySize:   10,
+		MaxValueSize: 5,
+		Filter: func(key, value string) (string, bool) {
+			return strings.ToUpper(value), value != "drop"
+		},
+		OnReject: func(key, value string, reason error) {
+			rejected[key] = reason
+		},
+	}
+	zt, _ := zipkin.NewTracer(nil)
+	tracer := Wrap(zt, WithBaggagePolicy(policy))
+
+	sp := tracer.StartSpan("test")
+	sp.SetBaggageItem("tenant", "acme")
+	sp.SetBaggageItem("user", "1")
+	sp.SetBaggageItem("x-very-long-key", "1")
+	sp.SetBaggageItem("x-a", "too long")
+	sp.SetBaggageItem("x-b", "drop")
+	sp.SetBaggageItem("x-c", "ok")
+	sp.SetBaggageItem("x-d", "full")
+	// replacing an existing item does not count against MaxItems
+	sp.SetBaggageItem("x-c", "new")
+
+	assert.Equal(t, "ACME", sp.BaggageItem("tenant"))
+	assert.Equal(t, "NEW", sp.BaggageItem("x-c"))
+	assert.Equal(t, map[string]error{
+		"user":            ErrBaggageKeyNotAllowed,
+		"x-very-long-key": ErrBaggageKeyTooLong,
+		"x-a":             ErrBaggageValueTooLong,
+		"x-b":             ErrBaggageItemDropped,
+		"x-d":             ErrBaggageTooManyItems,
+	}, rejected)
+}
+
+func TestBaggagePolicy_Propagation(t *testing.T) {
+	var rejected []string
+	policy := BaggagePolicy{
+		AllowedKeys:  []string{"a", "b", "c"},
+		MaxTotalSize: 4,
+		OnReject: func(key, value string, reason error) {
+			rejected = append(rejected, key)
+		},
+	}
+	zt, _ := zipkin.NewTracer(nil)
+	tracer := Wrap(zt, WithBaggagePolicy(policy))
+
+	c := http.Header{}
+	c.Set("ot-baggage-a", "1")
+	c.Set("ot-baggage-b", "2")
+	c.Set("ot-baggage-c", "3")
+	c.Set("ot-baggage-d", "4")
+
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(c))
+	assert.NoError(t, err)
+
+	items := map[string]string{}
+	sc.ForeachBaggageItem(func(k, v string) bool {
+		items[k] = v
+		return true
+	})
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, items)
+	assert.Equal(t, []string{"c", "d"}, rejected)
+
+	// the policy is enforced on inject as well
+	rejected = nil
+	unrestricted := Wrap(zt)
+	sp := unrestricted.StartSpan("test")
+	sp.SetBaggageItem("a", "1")
+	sp.SetBaggageItem("d", "4")
+
+	out := http.Header{}
+	assert.NoError(t, tracer.Inject(sp.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(out)))
+	assert.Equal(t, "1", out.Get("ot-baggage-a"))
+	assert.Equal(t, "", out.Get("ot-baggage-d"))
+	assert.Equal(t, []string{"d"}, rejected)
+}
@@ -22,4 +22,6 @@ import (
 type SpanContext model.SpanContext
 
 // ForeachBaggageItem belongs to the opentracing.SpanContext interface
-func (c SpanContext) ForeachBaggageItem(handler func(k, v string) bool) {}
+func (c SpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	newBaggage(c.Baggage).foreach(handler)
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// OpenTracing error log field keys, see
+// https://github.com/opentracing/specification/blob/master/semantic_conventions.md#log-fields-table
+const (
+	logFieldEvent       = "event"
+	logFieldErrorObject = "error.object"
+	logFieldErrorKind   = "error.kind"
+	logFieldMessage     = "message"
+	logFieldStack       = "stack"
+	logFieldPayload     = "payload"
+
+	errorEvent = "error"
+)
+
+// defaultErrorMessage is the value of the Zipkin error tag if no error
+// message is known.
+const defaultErrorMessage = "true"
+
+// parseErrorTag translates the value of an OpenTracing error tag. It returns
+// false if the value signals no error happened.
+func parseErrorTag(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case nil:
+		return "", false
+	case bool:
+		return "", v
+	case error:
+		if isNilPointer(v) {
+			return "", true
+		}
+		return v.Error(), true
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return "", b
+		}
+		return v, true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// isErrorLog reports whether the fields describe an error following the
+// OpenTracing log conventions.
+func isErrorLog(fields []log.Field) bool {
+	for _, field := range fields {
+		switch field.Key() {
+		case logFieldEvent:
+			if fmt.Sprint(field.Value()) == errorEvent {
+				return true
+			}
+		case logFieldErrorObject:
+			return true
+		}
+	}
+	return false
+}
+
+// errorLogMessage returns the error message of an error log. The message of
+// error.object is preferred over the message and error.kind fields.
+func errorLogMessage(fields []log.Field) string {
+	var message, kind string
+	for _, field := range fields {
+		switch field.Key() {
+		case logFieldErrorObject:
+			value := field.Value()
+			if value == nil || isNilPointer(value) {
+				continue
+			}
+			if err, ok := value.(error); ok {
+				return err.Error()
+			}
+			return fmt.Sprint(value)
+		case logFieldMessage:
+			message = fmt.Sprint(field.Value())
+		case logFieldErrorKind:
+			kind = fmt.Sprint(field.Value())
+		}
+	}
+	if message != "" {
+		return message
+	}
+	return kind
+}
+
+// isNilPointer reports whether v holds a nil pointer, on which Error or String
+// methods might panic.
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
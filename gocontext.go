@@ -0,0 +1,72 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+)
+
+// ContextWithSpanHook implements opentracing.TracerContextWithSpanExtension.
+// opentracing.ContextWithSpan stores the zipkin span backing the span in the
+// context as well, making it available to zipkin.SpanFromContext.
+func (t *tracerImpl) ContextWithSpanHook(ctx context.Context, span opentracing.Span) context.Context {
+	if zipkinSpan, ok := ZipkinSpan(span); ok {
+		return zipkin.NewContext(ctx, zipkinSpan)
+	}
+	return ctx
+}
+
+// StartSpanFromContext starts a span using the global tracer like
+// opentracing.StartSpanFromContext, falling back to the span stored in the
+// context by zipkin-go as parent.
+func StartSpanFromContext(
+	ctx context.Context, operationName string, opts ...opentracing.StartSpanOption,
+) (opentracing.Span, context.Context) {
+	return StartSpanFromContextWithTracer(ctx, opentracing.GlobalTracer(), operationName, opts...)
+}
+
+// StartSpanFromContextWithTracer starts a span using the provided tracer like
+// opentracing.StartSpanFromContextWithTracer, falling back to the span stored
+// in the context by zipkin-go as parent.
+func StartSpanFromContextWithTracer(
+	ctx context.Context, tracer opentracing.Tracer, operationName string, opts ...opentracing.StartSpanOption,
+) (opentracing.Span, context.Context) {
+	if parent := parentFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent))
+	}
+	span := tracer.StartSpan(operationName, opts...)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// parentFromContext returns the SpanContext of the innermost span stored in
+// the context by either OpenTracing or zipkin-go.
+func parentFromContext(ctx context.Context) opentracing.SpanContext {
+	span := opentracing.SpanFromContext(ctx)
+	zipkinSpan := zipkin.SpanFromContext(ctx)
+	if span != nil {
+		// ContextWithSpanHook stores both spans, a different zipkin span was
+		// stored by zipkin-go afterwards
+		if sp, ok := ZipkinSpan(span); !ok || zipkinSpan == nil || sp == zipkinSpan {
+			return span.Context()
+		}
+	}
+	if zipkinSpan != nil {
+		return SpanContext(zipkinSpan.Context())
+	}
+	return nil
+}
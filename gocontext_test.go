@@ -0,0 +1,61 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"context"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextWithSpanHook(t *testing.T) {
+	rec := recorder.NewReporter()
+	tracer := newTracer(rec)
+
+	span := tracer.StartSpan("x")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	zipkinSpan, _ := ZipkinSpan(span)
+	assert.Equal(t, zipkinSpan, zipkin.SpanFromContext(ctx))
+}
+
+func TestStartSpanFromContextWithTracer(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr)
+
+	// zipkin-go parent
+	native := tr.StartSpan("native")
+	ctx := zipkin.NewContext(context.Background(), native)
+	span, ctx := StartSpanFromContextWithTracer(ctx, tracer, "ot")
+	assert.Equal(t, native.Context().ID, *span.Context().(SpanContext).ParentID)
+
+	// OpenTracing parent visible to zipkin-go
+	child := tr.StartSpan("native-child", zipkin.Parent(zipkin.SpanFromContext(ctx).Context()))
+	assert.Equal(t, span.Context().(SpanContext).ID, *child.Context().ParentID)
+
+	// zipkin-go span stored after the OpenTracing span
+	ctx = zipkin.NewContext(ctx, child)
+	grandChild, _ := StartSpanFromContextWithTracer(ctx, tracer, "ot-grandchild")
+	assert.Equal(t, child.Context().ID, *grandChild.Context().(SpanContext).ParentID)
+
+	// no parent
+	root, _ := StartSpanFromContextWithTracer(context.Background(), tracer, "root")
+	assert.Nil(t, root.Context().(SpanContext).ParentID)
+}
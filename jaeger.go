@@ -0,0 +1,142 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// Jaeger header keys, see
+// https://www.jaegertracing.io/docs/latest/client-libraries/#propagation-format
+const (
+	jaegerTraceHeader   = "uber-trace-id"
+	jaegerBaggagePrefix = "uberctx-"
+)
+
+const (
+	jaegerSampledFlag = 0x01
+	jaegerDebugFlag   = 0x02
+)
+
+// NewJaegerPropagator returns the Propagator of the Jaeger uber-trace-id
+// header. Baggage items are injected as uberctx-<key> headers next to the
+// headers set by WithBaggageEncoding.
+func NewJaegerPropagator() Propagator {
+	return jaegerPropagator{}
+}
+
+type jaegerPropagator struct{}
+
+func (jaegerPropagator) Inject(sc model.SpanContext, set func(key, value string)) error {
+	if err := injectJaeger(sc, set); err != nil {
+		return err
+	}
+	injectJaegerBaggage(newBaggage(sc.Baggage), set)
+	return nil
+}
+
+func (jaegerPropagator) Extract(headers Headers) (*model.SpanContext, error) {
+	header := strings.TrimSpace(headers.Get(jaegerTraceHeader))
+	if header == "" {
+		return nil, nil
+	}
+	return parseUberTraceID(header)
+}
+
+// parseUberTraceID parses an uber-trace-id header of the form
+// {trace-id}:{span-id}:{parent-span-id}:{flags}. Jaeger clients URL encode
+// the header when using HTTP headers. The debug flag maps onto Debug, which
+// implies sampling.
+func parseUberTraceID(header string) (*model.SpanContext, error) {
+	if strings.IndexByte(header, '%') >= 0 {
+		v, err := url.QueryUnescape(header)
+		if err != nil {
+			return nil, opentracing.ErrSpanContextCorrupted
+		}
+		header = v
+	}
+	parts := strings.Split(header, ":")
+	if len(parts) != 4 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	traceID, err := model.TraceIDFromHex(parts[0])
+	if err != nil || traceID.Empty() {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	id, err := strconv.ParseUint(parts[1], 16, 64)
+	if err != nil || id == 0 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	parentID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	sc := &model.SpanContext{
+		TraceID: traceID,
+		ID:      model.ID(id),
+	}
+	if parentID != 0 {
+		pid := model.ID(parentID)
+		sc.ParentID = &pid
+	}
+	if flags&jaegerDebugFlag == jaegerDebugFlag {
+		sc.Debug = true
+	} else {
+		sampled := flags&jaegerSampledFlag == jaegerSampledFlag
+		sc.Sampled = &sampled
+	}
+	return sc, nil
+}
+
+// injectJaeger writes the uber-trace-id header. Jaeger has no deferred
+// sampling decision, spans without decision are flagged as not sampled.
+func injectJaeger(sc model.SpanContext, set func(key, value string)) error {
+	if sc.TraceID.Empty() || sc.ID == 0 {
+		return opentracing.ErrInvalidSpanContext
+	}
+	var flags byte
+	if sc.Sampled != nil && *sc.Sampled {
+		flags |= jaegerSampledFlag
+	}
+	if sc.Debug {
+		flags |= jaegerSampledFlag | jaegerDebugFlag
+	}
+	parentID := "0"
+	if sc.ParentID != nil {
+		parentID = sc.ParentID.String()
+	}
+	set(jaegerTraceHeader, fmt.Sprintf("%s:%s:%s:%x", sc.TraceID, sc.ID, parentID, flags))
+	return nil
+}
+
+// injectJaegerBaggage writes one uberctx-<key> header per baggage item.
+func injectJaegerBaggage(b *baggage, set func(key, value string)) {
+	b.foreach(func(k, v string) bool {
+		set(jaegerBaggagePrefix+k, url.QueryEscape(v))
+		return true
+	})
+}
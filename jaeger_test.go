@@ -0,0 +1,154 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUberTraceID(t *testing.T) {
+	sampled, unsampled := true, false
+	parentID := model.ID(0x2)
+	testCases := []struct {
+		header   string
+		expected *model.SpanContext
+	}{
+		{
+			"4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			&model.SpanContext{
+				TraceID: model.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736},
+				ID:      0x00f067aa0ba902b7,
+				Sampled: &sampled,
+			},
+		},
+		// leading zeros are optional
+		{
+			"a3ce929d0e0e4736:f067aa0ba902b7:2:0",
+			&model.SpanContext{
+				TraceID:  model.TraceID{Low: 0xa3ce929d0e0e4736},
+				ID:       0x00f067aa0ba902b7,
+				ParentID: &parentID,
+				Sampled:  &unsampled,
+			},
+		},
+		{
+			"a3ce929d0e0e4736%3Af067aa0ba902b7%3A0%3A3",
+			&model.SpanContext{
+				TraceID: model.TraceID{Low: 0xa3ce929d0e0e4736},
+				ID:      0x00f067aa0ba902b7,
+				Debug:   true,
+			},
+		},
+		{"0:00f067aa0ba902b7:0:1", nil},
+		{"a3ce929d0e0e4736:0:0:1", nil},
+		{"a3ce929d0e0e4736:00f067aa0ba902b7:0", nil},
+		{"a3ce929d0e0e4736:00f067aa0ba902b7:0:1:0", nil},
+		{"a3ce929d0e0e4736:invalid:0:1", nil},
+		{"4bf92f3577b34da6a3ce929d0e0e47360:00f067aa0ba902b7:0:1", nil},
+		{"a3ce929d0e0e4736%zz00f067aa0ba902b7:0:1", nil},
+	}
+	for _, tc := range testCases {
+		sc, err := parseUberTraceID(tc.header)
+		if tc.expected == nil {
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, tc.header)
+			continue
+		}
+		assert.NoError(t, err, tc.header)
+		assert.Equal(t, tc.expected, sc, tc.header)
+	}
+}
+
+func TestInjectJaeger(t *testing.T) {
+	sampled := true
+	parentID := model.ID(0x2)
+	testCases := []struct {
+		sc       model.SpanContext
+		expected string
+	}{
+		{
+			model.SpanContext{TraceID: model.TraceID{Low: 0xa}, ID: 0xb, Sampled: &sampled},
+			"000000000000000a:000000000000000b:0:1",
+		},
+		{
+			model.SpanContext{TraceID: model.TraceID{High: 0x1, Low: 0xa}, ID: 0xb, ParentID: &parentID},
+			"0000000000000001000000000000000a:000000000000000b:0000000000000002:0",
+		},
+		{
+			model.SpanContext{TraceID: model.TraceID{Low: 0xa}, ID: 0xb, Debug: true},
+			"000000000000000a:000000000000000b:0:3",
+		},
+	}
+	for _, tc := range testCases {
+		carrier := opentracing.TextMapCarrier{}
+		assert.NoError(t, injectJaeger(tc.sc, carrier.Set))
+		assert.Equal(t, tc.expected, carrier[jaegerTraceHeader])
+	}
+
+	assert.Equal(t, opentracing.ErrInvalidSpanContext, injectJaeger(model.SpanContext{}, nil))
+}
+
+func TestJaegerPropagation(t *testing.T) {
+	tracer := Wrap(mustTracer(t), WithPropagationFormat(PropagationJaeger))
+
+	// a Jaeger client using HTTP headers URL encodes the values
+	carrier := opentracing.HTTPHeadersCarrier(http.Header{
+		"Uber-Trace-Id":    {"4bf92f3577b34da6a3ce929d0e0e4736%3A00f067aa0ba902b7%3A0%3A3"},
+		"Uberctx-Customer": {"acme%20corp"},
+	})
+	parent, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.NoError(t, err)
+
+	span := tracer.StartSpan("x", opentracing.ChildOf(parent))
+	assert.Equal(t, "acme corp", span.BaggageItem("customer"))
+
+	textMap := opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, textMap))
+	sc := span.Context().(SpanContext)
+	assert.Equal(t, opentracing.TextMapCarrier{
+		"uber-trace-id":       "4bf92f3577b34da6a3ce929d0e0e4736:" + sc.ID.String() + ":00f067aa0ba902b7:3",
+		"uberctx-customer":    "acme+corp",
+		"ot-baggage-customer": "acme+corp",
+	}, textMap)
+	span.Finish()
+
+	extracted, err := tracer.Extract(opentracing.TextMap, textMap)
+	assert.NoError(t, err)
+	assert.Equal(t, sc.TraceID, extracted.(SpanContext).TraceID)
+	assert.Equal(t, sc.ID, extracted.(SpanContext).ID)
+	assert.True(t, extracted.(SpanContext).Debug)
+}
+
+func TestB3AndJaegerPropagation(t *testing.T) {
+	tracer := Wrap(mustTracer(t), WithPropagationFormat(PropagationB3|PropagationJaeger))
+
+	// a request crossing a Jaeger instrumented service stays in the trace
+	parent, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		"uber-trace-id": "a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+	})
+	assert.NoError(t, err)
+	span := tracer.StartSpan("x", opentracing.ChildOf(parent))
+
+	carrier := opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, carrier))
+	assert.Equal(t, "a3ce929d0e0e4736", carrier["x-b3-traceid"])
+	assert.Equal(t, "00f067aa0ba902b7", carrier["x-b3-parentspanid"])
+	assert.Equal(t, "1", carrier["x-b3-sampled"])
+	assert.Contains(t, carrier["uber-trace-id"], "a3ce929d0e0e4736:")
+}
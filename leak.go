@@ -0,0 +1,163 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// LeakedKey is the tag set on leaked spans finished by the leak detector.
+const LeakedKey = "leaked"
+
+// LeakDetection configures the detection of spans which are not finished,
+// e.g. because of a missing defer span.Finish().
+type LeakDetection struct {
+	// Threshold is the duration after which an unfinished span is reported
+	// as leaked.
+	Threshold time.Duration
+	// CheckInterval is the interval between checks for leaked spans. It
+	// defaults to Threshold.
+	CheckInterval time.Duration
+	// CaptureStacks records the stack each span is started from. This is
+	// expensive and meant for debugging.
+	CaptureStacks bool
+	// FinishLeaked finishes leaked spans with the LeakedKey tag set to true.
+	FinishLeaked bool
+	// OnLeak is called once for each leaked span. Leaked spans are reported
+	// to the Logger set by WithLogger, within its rate, or the standard
+	// logger if not set.
+	// Unless finished, leaked spans are no longer held by the tracer nor
+	// awaited by Flush and Close once reported.
+	OnLeak func(span LeakedSpan)
+}
+
+// LeakedSpan describes a span which is not finished.
+type LeakedSpan struct {
+	Span          opentracing.Span
+	OperationName string
+	// StartTime is the time the span was started through the tracer.
+	StartTime time.Time
+	// Stack is the stack the span was started from if captured.
+	Stack []byte
+}
+
+// detectLeaks periodically reports leaked spans until stop is closed.
+func (t *tracerImpl) detectLeaks(stop <-chan struct{}) {
+	interval := t.opts.leakDetection.CheckInterval
+	if interval <= 0 {
+		interval = t.opts.leakDetection.Threshold
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			t.reportLeaks(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reportLeaks reports the spans open longer than the leak threshold at the
+// provided time.
+func (t *tracerImpl) reportLeaks(now time.Time) {
+	d := t.opts.leakDetection
+	for _, leaked := range t.leakedSpans(now.Add(-d.Threshold)) {
+		sp := leaked.Span.(*spanImpl)
+		if !atomic.CompareAndSwapInt32(&sp.leakReported, 0, 1) {
+			continue
+		}
+		if d.OnLeak != nil {
+			d.OnLeak(leaked)
+		} else if operationName := t.opts.sanitizeOperationName(leaked.OperationName); t.logger != nil {
+			t.logger.warn("span is not finished", "operation", operationName,
+				"start_time", leaked.StartTime, "stack", string(leaked.Stack))
+		} else {
+			log.Printf("zipkintracer: span %q started at %s is not finished\n%s",
+				operationName, leaked.StartTime.Format(time.RFC3339Nano), leaked.Stack)
+		}
+		if d.FinishLeaked {
+			sp.SetTag(LeakedKey, true)
+			sp.Finish()
+		} else {
+			// the span might never be finished, Flush and Close no longer
+			// wait for it
+			t.spans.done(sp)
+		}
+	}
+}
+
+// leakedSpans returns the spans started before the provided time which are
+// not finished, ordered by start time.
+func (t *tracerImpl) leakedSpans(startedBefore time.Time) []LeakedSpan {
+	var leaked []LeakedSpan
+	for sp, ts := range t.spans.snapshot() {
+		if !ts.since.Before(startedBefore) {
+			continue
+		}
+		sp.mtx.RLock()
+		operationName := sp.operationName
+		sp.mtx.RUnlock()
+		leaked = append(leaked, LeakedSpan{
+			Span:          sp,
+			OperationName: operationName,
+			StartTime:     ts.since,
+			Stack:         ts.stack,
+		})
+	}
+	sort.Slice(leaked, func(i, j int) bool {
+		return leaked[i].StartTime.Before(leaked[j].StartTime)
+	})
+	return leaked
+}
+
+// TestingT is the subset of testing.TB used by AssertNoLeakedSpans.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertNoLeakedSpans reports an error to t for each span started by the
+// tracer which is not finished. The tracer must be returned by Wrap. Details
+// such as the operation name are only reported for spans held using
+// WithLeakDetection, other spans are counted. Spans already reported by the
+// leak detector are not included.
+func AssertNoLeakedSpans(t TestingT, tracer opentracing.Tracer) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	tr, ok := tracer.(*tracerImpl)
+	if !ok {
+		t.Errorf("tracer %T was not returned by Wrap", tracer)
+		return false
+	}
+	leaked := tr.leakedSpans(time.Now().Add(time.Nanosecond))
+	for _, sp := range leaked {
+		t.Errorf("span %q started at %s is not finished\n%s",
+			sp.OperationName, sp.StartTime.Format(time.RFC3339Nano), sp.Stack)
+	}
+	// spans are only held using WithLeakDetection and until reported
+	if n := tr.spans.len() - int64(len(leaked)); n > 0 {
+		t.Errorf("unfinished spans: %d", n)
+		return false
+	}
+	return len(leaked) == 0
+}
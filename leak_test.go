@@ -0,0 +1,122 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeakDetection(t *testing.T) {
+	var leaked []LeakedSpan
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithLeakDetection(LeakDetection{
+		Threshold:     time.Hour,
+		CaptureStacks: true,
+		FinishLeaked:  true,
+		OnLeak:        func(span LeakedSpan) { leaked = append(leaked, span) },
+	}))
+	defer tracer.(io.Closer).Close()
+
+	tracer.StartSpan("finished").Finish()
+	tracer.StartSpan("leaked")
+
+	impl := tracer.(*tracerImpl)
+	impl.reportLeaks(time.Now())
+	assert.Empty(t, leaked)
+
+	impl.reportLeaks(time.Now().Add(2 * time.Hour))
+	impl.reportLeaks(time.Now().Add(3 * time.Hour))
+	assert.Equal(t, 1, len(leaked))
+	assert.Equal(t, "leaked", leaked[0].OperationName)
+	assert.True(t, strings.Contains(string(leaked[0].Stack), "TestLeakDetection"))
+
+	spans := rec.Flush()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "leaked", spans[1].Name)
+	assert.Equal(t, "true", spans[1].Tags[LeakedKey])
+}
+
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertNoLeakedSpans(t *testing.T) {
+	tr, _ := zipkin.NewTracer(recorder.NewReporter())
+	tracer := Wrap(tr, WithLeakDetection(LeakDetection{}))
+
+	span := tracer.StartSpan("x")
+	rt := &recordingT{}
+	assert.False(t, AssertNoLeakedSpans(rt, tracer))
+	assert.Equal(t, 1, len(rt.errors))
+	assert.True(t, strings.HasPrefix(rt.errors[0], `span "x" started at`))
+
+	span.Finish()
+	assert.True(t, AssertNoLeakedSpans(t, tracer))
+
+	// without leak detection spans are counted only
+	tracer = newTracer(recorder.NewReporter())
+	span = tracer.StartSpan("x")
+	rt = &recordingT{}
+	assert.False(t, AssertNoLeakedSpans(rt, tracer))
+	assert.Equal(t, []string{"unfinished spans: 1"}, rt.errors)
+	assert.Empty(t, tracer.(*tracerImpl).spans.snapshot())
+
+	span.Finish()
+	assert.True(t, AssertNoLeakedSpans(t, tracer))
+}
+
+func TestLeakedSpansNotHeld(t *testing.T) {
+	var leaked int
+	tr, _ := zipkin.NewTracer(recorder.NewReporter())
+	tracer := Wrap(tr, WithLeakDetection(LeakDetection{
+		Threshold: time.Hour,
+		OnLeak:    func(LeakedSpan) { leaked++ },
+	}))
+
+	var spans []opentracing.Span
+	for i := 0; i < 1000; i++ {
+		spans = append(spans, tracer.StartSpan("leaked"))
+	}
+	impl := tracer.(*tracerImpl)
+	assert.Equal(t, 1000, len(impl.spans.snapshot()))
+
+	impl.reportLeaks(time.Now().Add(2 * time.Hour))
+	assert.Equal(t, 1000, leaked)
+	assert.Empty(t, impl.spans.snapshot())
+	assert.Equal(t, int64(0), impl.spans.len())
+
+	// spans reported as leaked are not awaited
+	start := time.Now()
+	assert.NoError(t, tracer.(io.Closer).Close())
+	assert.True(t, time.Since(start) < time.Second)
+
+	// nor counted twice once finished
+	spans[0].Finish()
+	assert.Equal(t, int64(0), impl.spans.len())
+}
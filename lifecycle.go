@@ -0,0 +1,174 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCloseTimeout is the time Close waits for in-flight spans to finish.
+const DefaultCloseTimeout = 5 * time.Second
+
+// Flush waits for in-flight spans to finish until the context is done and
+// then closes the reporter set by WithReporter. Spans finished afterwards are
+// not reported. Calling Flush or Close more than once has no further effect.
+func (t *tracerImpl) Flush(ctx context.Context) error {
+	err := t.spans.wait(ctx)
+	atomic.StoreInt32(&t.closed, 1)
+
+	t.closeOnce.Do(func() {
+		if t.stopLeakDetection != nil {
+			close(t.stopLeakDetection)
+		}
+		if t.opts.reporter != nil {
+			t.closeErr = t.opts.reporter.Close()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return t.closeErr
+}
+
+// Close implements io.Closer. It calls Flush with a deadline of
+// DefaultCloseTimeout.
+func (t *tracerImpl) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCloseTimeout)
+	defer cancel()
+	return t.Flush(ctx)
+}
+
+// isClosed reports whether the tracer was flushed or closed.
+func (t *tracerImpl) isClosed() bool {
+	return atomic.LoadInt32(&t.closed) == 1
+}
+
+// track records a started span in the tracer Stats and until it is finished.
+// Spans wrapped by FromZipkinSpan might be finished through the zipkin span
+// and are not counted as in-flight.
+func (t *tracerImpl) track(sp *spanImpl) {
+	t.stats.SpansStarted.kind(sp.kind).inc()
+	if sc := sp.zipkinSpan.Context(); sc.Debug || (sc.Sampled != nil && *sc.Sampled) {
+		t.stats.SpansSampled.inc()
+	} else {
+		t.stats.SpansUnsampled.inc()
+	}
+	if sp.selfReporting {
+		return
+	}
+
+	var stack []byte
+	if d := t.opts.leakDetection; d != nil && d.CaptureStacks {
+		stack = debug.Stack()
+	}
+	t.spans.start(sp, stack)
+}
+
+// spanTracker tracks the in-flight spans of a tracer. Spans are counted and
+// only held, along with the stack they were started from, if hold is set.
+type spanTracker struct {
+	// inFlight is accessed atomically and must stay 64-bit aligned
+	inFlight int64
+	hold     bool
+
+	mtx    sync.Mutex
+	active map[*spanImpl]trackedSpan
+	idle   chan struct{}
+}
+
+type trackedSpan struct {
+	since time.Time
+	stack []byte
+}
+
+// start records a started span.
+func (t *spanTracker) start(sp *spanImpl, stack []byte) {
+	atomic.StoreInt32(&sp.tracked, 1)
+	atomic.AddInt64(&t.inFlight, 1)
+	if !t.hold {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.active == nil {
+		t.active = make(map[*spanImpl]trackedSpan)
+	}
+	t.active[sp] = trackedSpan{since: time.Now(), stack: stack}
+}
+
+// done records a finished span or one which is no longer tracked, e.g. once
+// it was reported as leaked. Spans which are not tracked are ignored.
+func (t *spanTracker) done(sp *spanImpl) {
+	if !atomic.CompareAndSwapInt32(&sp.tracked, 1, 0) {
+		return
+	}
+	inFlight := atomic.AddInt64(&t.inFlight, -1)
+	if inFlight > 0 && !t.hold {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	delete(t.active, sp)
+	if inFlight == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// len returns the number of in-flight spans.
+func (t *spanTracker) len() int64 {
+	return atomic.LoadInt64(&t.inFlight)
+}
+
+// snapshot returns the in-flight spans held by the tracker.
+func (t *spanTracker) snapshot() map[*spanImpl]trackedSpan {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	active := make(map[*spanImpl]trackedSpan, len(t.active))
+	for sp, ts := range t.active {
+		active[sp] = ts
+	}
+	return active
+}
+
+// wait blocks until no span is in-flight or the context is done.
+func (t *spanTracker) wait(ctx context.Context) error {
+	t.mtx.Lock()
+	if t.len() == 0 {
+		t.mtx.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mtx.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
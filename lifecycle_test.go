@@ -0,0 +1,74 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+type closeCountingReporter struct {
+	*recorder.ReporterRecorder
+	closed int32
+}
+
+func (r *closeCountingReporter) Close() error {
+	atomic.AddInt32(&r.closed, 1)
+	return nil
+}
+
+func TestTracerFlush(t *testing.T) {
+	rec := &closeCountingReporter{ReporterRecorder: recorder.NewReporter()}
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithReporter(rec))
+
+	span := tracer.StartSpan("x")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		span.Finish()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, tracer.(interface{ Flush(context.Context) error }).Flush(ctx))
+	assert.Equal(t, 1, len(rec.Flush()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rec.closed))
+
+	// spans finished after Flush are not reported
+	tracer.StartSpan("y").Finish()
+	assert.NoError(t, tracer.(io.Closer).Close())
+	assert.Equal(t, 0, len(rec.Flush()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rec.closed))
+}
+
+func TestTracerFlushDeadline(t *testing.T) {
+	rec := &closeCountingReporter{ReporterRecorder: recorder.NewReporter()}
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithReporter(rec))
+
+	tracer.StartSpan("unfinished")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, tracer.(interface{ Flush(context.Context) error }).Flush(ctx))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rec.closed))
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// LogEncoder encodes the fields of a single LogFields, LogKV or Log call into
+// the value of one Zipkin annotation.
+type LogEncoder func(fields []log.Field) string
+
+// KeyValueLogEncoder encodes log fields as space separated key=value pairs,
+// e.g. event=cache-miss key=x ttl=3. Values holding spaces, quotes or equal
+// signs are quoted.
+func KeyValueLogEncoder(fields []log.Field) string {
+	var sb strings.Builder
+	for _, kv := range collectLogFields(fields) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(kv.key)
+		sb.WriteByte('=')
+		value := fmt.Sprint(kv.value)
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			value = strconv.Quote(value)
+		}
+		sb.WriteString(value)
+	}
+	return sb.String()
+}
+
+// JSONLogEncoder encodes log fields as a compact JSON object, e.g.
+// {"event":"cache-miss","key":"x","ttl":3}. Field order is preserved.
+func JSONLogEncoder(fields []log.Field) string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, kv := range collectLogFields(fields) {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		key, _ := json.Marshal(kv.key)
+		sb.Write(key)
+		sb.WriteByte(':')
+		value, err := json.Marshal(kv.value)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(kv.value))
+		}
+		sb.Write(value)
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+type logKeyValue struct {
+	key   string
+	value interface{}
+}
+
+// collectLogFields returns the key value pairs of the fields, resolving lazy
+// loggers and errors.
+func collectLogFields(fields []log.Field) []logKeyValue {
+	c := &logFieldCollector{}
+	for _, field := range fields {
+		if err, ok := field.Value().(error); ok && isNilPointer(err) {
+			// Marshal calls the Error method of the nil pointer
+			c.EmitError(field.Key(), nil)
+			continue
+		}
+		field.Marshal(c)
+	}
+	return c.kvs
+}
+
+// logFieldCollector implements log.Encoder
+type logFieldCollector struct {
+	kvs []logKeyValue
+}
+
+func (c *logFieldCollector) emit(key string, value interface{}) {
+	c.kvs = append(c.kvs, logKeyValue{key: key, value: value})
+}
+
+func (c *logFieldCollector) EmitString(key, value string)             { c.emit(key, value) }
+func (c *logFieldCollector) EmitBool(key string, value bool)          { c.emit(key, value) }
+func (c *logFieldCollector) EmitInt(key string, value int)            { c.emit(key, value) }
+func (c *logFieldCollector) EmitInt32(key string, value int32)        { c.emit(key, value) }
+func (c *logFieldCollector) EmitInt64(key string, value int64)        { c.emit(key, value) }
+func (c *logFieldCollector) EmitUint32(key string, value uint32)      { c.emit(key, value) }
+func (c *logFieldCollector) EmitUint64(key string, value uint64)      { c.emit(key, value) }
+func (c *logFieldCollector) EmitFloat32(key string, value float32)    { c.emit(key, value) }
+func (c *logFieldCollector) EmitFloat64(key string, value float64)    { c.emit(key, value) }
+func (c *logFieldCollector) EmitObject(key string, value interface{}) { c.emit(key, value) }
+func (c *logFieldCollector) EmitLazyLogger(value log.LazyLogger)      { value(c) }
+
+func (c *logFieldCollector) EmitError(key string, value error) {
+	if value == nil || isNilPointer(value) {
+		c.emit(key, nil)
+		return
+	}
+	c.emit(key, value.Error())
+}
+
+// truncateLogFields replaces fields with a value longer than maxLength bytes
+// by string fields holding the truncated value.
+func truncateLogFields(fields []log.Field, maxLength int) []log.Field {
+	if maxLength <= 0 {
+		return fields
+	}
+	var truncated []log.Field
+	for i, field := range fields {
+		value, ok := field.Value().(string)
+		if !ok {
+			value = fmt.Sprint(field.Value())
+		}
+		if len(value) <= maxLength {
+			continue
+		}
+		if truncated == nil {
+			truncated = make([]log.Field, len(fields))
+			copy(truncated, fields)
+		}
+		truncated[i] = log.String(field.Key(), truncate(value, maxLength))
+	}
+	if truncated == nil {
+		return fields
+	}
+	return truncated
+}
+
+// truncate shortens s to at most maxLength bytes without splitting runes and
+// marks the truncation with an ellipsis. A maxLength of zero disables
+// truncation.
+func truncate(s string, maxLength int) string {
+	const ellipsis = "..."
+	if maxLength <= 0 || len(s) <= maxLength {
+		return s
+	}
+	cut, suffix := maxLength, ""
+	if maxLength > len(ellipsis) {
+		cut, suffix = maxLength-len(ellipsis), ellipsis
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + suffix
+}
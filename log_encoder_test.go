@@ -0,0 +1,75 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyValueLogEncoder(t *testing.T) {
+	fields := []log.Field{
+		log.String("event", "cache-miss"),
+		log.String("key", "x y"),
+		log.Int("ttl", 3),
+		log.Error(errors.New("boom")),
+		log.Lazy(func(fv log.Encoder) { fv.EmitBool("lazy", true) }),
+	}
+	assert.Equal(t, `event=cache-miss key="x y" ttl=3 error.object=boom lazy=true`, KeyValueLogEncoder(fields))
+}
+
+func TestJSONLogEncoder(t *testing.T) {
+	fields := []log.Field{
+		log.String("event", "cache-miss"),
+		log.Int("ttl", 3),
+		log.Float64("ratio", 0.5),
+		log.Object("obj", map[string]int{"a": 1}),
+		log.Object("func", func() {}),
+	}
+	value := JSONLogEncoder(fields)
+	assert.True(t, strings.HasPrefix(value, `{"event":"cache-miss","ttl":3,"ratio":0.5,"obj":{"a":1},"func":"0x`), value)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abcdef", truncate("abcdef", 0))
+	assert.Equal(t, "abcdef", truncate("abcdef", 6))
+	assert.Equal(t, "ab...", truncate("abcdef", 5))
+	assert.Equal(t, "ab", truncate("abcdef", 2))
+	assert.Equal(t, "...", truncate("ééé", 4))
+	assert.Equal(t, "é...", truncate("ééé", 5))
+}
+
+func TestSpan_LogEncoder(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithLogEncoder(KeyValueLogEncoder), WithMaxLogValueLength(10))
+
+	span := tracer.StartSpan("x")
+	span.LogKV("event", "cache-miss", "key", "a-very-long-key", "ttl", 3)
+	span.LogEventWithPayload("event", 123)
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, 2, len(spans[0].Annotations))
+	assert.Equal(t, "event=cache-miss key=a-very-... ttl=3", spans[0].Annotations[0].Value)
+	assert.Equal(t, "event=event payload=123", spans[0].Annotations[1].Value)
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLogRate is the maximum number of messages per second the tracer
+// reports through the Logger set by WithLogger.
+const DefaultLogRate = 10
+
+// Logger reports errors the tracer can not return to the caller and data it
+// drops or coerces. Arguments following the message are alternating keys and
+// values. *slog.Logger implements Logger.
+type Logger interface {
+	Warn(msg string, keyvals ...interface{})
+}
+
+// rateLimitedLogger reports through a Logger at a limited rate. Suppressed
+// messages are counted and the count is added to the next reported message.
+// A nil rateLimitedLogger discards all messages.
+type rateLimitedLogger struct {
+	// suppressed is accessed atomically and must stay the first field to be
+	// 64-bit aligned on 32-bit platforms
+	suppressed int64
+	logger     Logger
+	limiter    *rateLimiter
+}
+
+func newRateLimitedLogger(logger Logger, perSecond float64) *rateLimitedLogger {
+	if logger == nil {
+		return nil
+	}
+	return &rateLimitedLogger{
+		logger:  logger,
+		limiter: newRateLimiter(perSecond, time.Now()),
+	}
+}
+
+func (l *rateLimitedLogger) warn(msg string, keyvals ...interface{}) {
+	if l == nil {
+		return
+	}
+	if !l.limiter.allow(time.Now()) {
+		atomic.AddInt64(&l.suppressed, 1)
+		return
+	}
+	if suppressed := atomic.SwapInt64(&l.suppressed, 0); suppressed > 0 {
+		keyvals = append(keyvals, "suppressed", suppressed)
+	}
+	l.logger.Warn(msg, keyvals...)
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package zipkintracer
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogLogger returns a Logger reporting through logger at the provided
+// level.
+func NewSlogLogger(logger *slog.Logger, level slog.Level) Logger {
+	return &slogLogger{logger: logger, level: level}
+}
+
+func (l *slogLogger) Warn(msg string, keyvals ...interface{}) {
+	l.logger.Log(context.Background(), l.level, msg, keyvals...)
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	mtx      sync.Mutex
+	messages []string
+	keyvals  [][]interface{}
+}
+
+func (l *recordingLogger) Warn(msg string, keyvals ...interface{}) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.messages = append(l.messages, msg)
+	l.keyvals = append(l.keyvals, keyvals)
+}
+
+type failingTextMapReader struct{}
+
+func (failingTextMapReader) ForeachKey(func(key, val string) error) error {
+	return errors.New("read failed")
+}
+
+func TestLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr,
+		WithLogger(logger),
+		WithSpanLimits(SpanLimits{MaxTags: 2}),
+		WithBaggagePolicy(BaggagePolicy{AllowedKeys: []string{"allowed"}}),
+	)
+
+	span := tracer.StartSpan("x", opentracing.Tag{Key: string(ext.PeerPort), Value: "http"})
+	span.LogKV("odd")
+	span.SetTag(string(ext.PeerHostIPv4), "not-an-ip")
+	span.SetTag("dropped", true)
+	span.SetBaggageItem("secret", "x")
+	span.Finish()
+	_, _ = tracer.Extract(opentracing.TextMap, failingTextMapReader{})
+
+	assert.Equal(t, []string{
+		"unsupported peer tag value",
+		"dropped invalid LogKV key values",
+		"unsupported tag value",
+		"dropped tag exceeding span limits",
+		"rejected baggage item",
+		"failed to read carrier",
+	}, logger.messages)
+	// tag values are not sanitized and only logged by type
+	assert.Equal(t, []interface{}{"key", string(ext.PeerPort), "type", "string"}, logger.keyvals[0])
+	assert.Equal(t, []interface{}{"key", string(ext.PeerHostIPv4), "type", "string"}, logger.keyvals[2])
+	assert.Equal(t, []interface{}{"key", "secret", "reason", ErrBaggageKeyNotAllowed}, logger.keyvals[4])
+
+	// coerced values are kept as tags
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, map[string]string{
+		string(ext.PeerPort):     "http",
+		string(ext.PeerHostIPv4): "not-an-ip",
+		DroppedTagsKey:           "1",
+	}, spans[0].Tags)
+	assert.Nil(t, spans[0].RemoteEndpoint)
+}
+
+func TestRateLimitedLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	l := newRateLimitedLogger(logger, 2)
+
+	for i := 0; i < 5; i++ {
+		l.warn("message", "i", i)
+	}
+	l.limiter.balance = 1
+	l.warn("message", "i", 5)
+
+	assert.Equal(t, [][]interface{}{
+		{"i", 0},
+		{"i", 1},
+		{"i", 5, "suppressed", int64(3)},
+	}, logger.keyvals)
+
+	var disabled *rateLimitedLogger
+	disabled.warn("message")
+}
+
+func TestLoggerSanitizedOperationNames(t *testing.T) {
+	logger := &recordingLogger{}
+	tr, _ := zipkin.NewTracer(recorder.NewReporter())
+	tracer := Wrap(tr,
+		WithLogger(logger),
+		WithSanitizer(NewRedactor()),
+		WithMaxOperationNames(1, "other"),
+		WithLeakDetection(LeakDetection{Threshold: time.Hour}),
+	)
+	defer tracer.(io.Closer).Close()
+
+	tracer.StartSpan("first").Finish()
+	span := tracer.StartSpan("reset alice@example.com")
+	tracer.(*tracerImpl).reportLeaks(time.Now().Add(2 * time.Hour))
+	span.Finish()
+
+	assert.Equal(t, []string{
+		"operation name exceeds the operation name limit",
+		"span is not finished",
+	}, logger.messages)
+	assert.Equal(t, "reset "+RedactedMask, logger.keyvals[0][1])
+	assert.Equal(t, "reset "+RedactedMask, logger.keyvals[1][1])
+}
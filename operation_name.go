@@ -0,0 +1,106 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"regexp"
+	"sync"
+)
+
+// OriginalOperationNameKey is the tag holding the operation name of spans
+// whose name collapsed into the overflow name set by WithMaxOperationNames.
+const OriginalOperationNameKey = "operation.original_name"
+
+// OperationNameNormalizer rewrites operation names before they are recorded,
+// e.g. to replace identifiers which would explode the cardinality of span
+// names.
+type OperationNameNormalizer func(name string) string
+
+var (
+	uuidPattern    = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	hexHashPattern = regexp.MustCompile(`\b[0-9a-fA-F]{16,}\b`)
+	numericPattern = regexp.MustCompile(`\b[0-9]+\b`)
+)
+
+// DefaultOperationNameNormalizer replaces UUIDs with {uuid}, hexadecimal
+// hashes of at least 16 characters with {hash} and numeric identifiers with
+// {id}, e.g. "GET /users/12345" becomes "GET /users/{id}".
+func DefaultOperationNameNormalizer(name string) string {
+	name = uuidPattern.ReplaceAllString(name, "{uuid}")
+	name = hexHashPattern.ReplaceAllString(name, "{hash}")
+	return numericPattern.ReplaceAllString(name, "{id}")
+}
+
+// operationNameGuard caps the number of distinct operation names. A nil
+// operationNameGuard accepts every name.
+type operationNameGuard struct {
+	max      int
+	overflow string
+
+	mtx   sync.RWMutex
+	names map[string]struct{}
+}
+
+func newOperationNameGuard(max int, overflow string) *operationNameGuard {
+	if max <= 0 {
+		return nil
+	}
+	return &operationNameGuard{
+		max:      max,
+		overflow: overflow,
+		names:    make(map[string]struct{}, max),
+	}
+}
+
+// admit reports whether the name is one of the first max distinct names.
+func (g *operationNameGuard) admit(name string) bool {
+	if g == nil {
+		return true
+	}
+	g.mtx.RLock()
+	_, known := g.names[name]
+	full := len(g.names) >= g.max
+	g.mtx.RUnlock()
+	if known {
+		return true
+	}
+	if full {
+		return false
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if _, known = g.names[name]; known {
+		return true
+	}
+	if len(g.names) >= g.max {
+		return false
+	}
+	g.names[name] = struct{}{}
+	return true
+}
+
+// operationName returns the sanitized and normalized operation name to
+// record. It reports whether the name collapsed into the overflow name.
+func (t *tracerImpl) operationName(name string) (string, bool) {
+	name = t.opts.sanitizeOperationName(name)
+	if t.opts.operationNameNormalizer != nil {
+		name = t.opts.operationNameNormalizer(name)
+	}
+	if !t.operationNames.admit(name) {
+		return t.operationNames.overflow, true
+	}
+	return name, false
+}
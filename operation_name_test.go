@@ -0,0 +1,65 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOperationNameNormalizer(t *testing.T) {
+	testCases := map[string]string{
+		"GET /users/12345":                                    "GET /users/{id}",
+		"/api/v2/orders/42/items/7":                           "/api/v2/orders/{id}/items/{id}",
+		"/files/123e4567-e89b-12d3-a456-426614174000":         "/files/{uuid}",
+		"/blobs/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b": "/blobs/{hash}",
+		"/blobs/cafe":                                         "/blobs/cafe",
+		"get_user":                                            "get_user",
+	}
+	for name, expected := range testCases {
+		assert.Equal(t, expected, DefaultOperationNameNormalizer(name), name)
+	}
+}
+
+func TestOperationNameLimit(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr,
+		WithOperationNameNormalizer(DefaultOperationNameNormalizer),
+		WithMaxOperationNames(2, "overflow"),
+	)
+
+	tracer.StartSpan("/users/1").Finish()
+	tracer.StartSpan("/users/2").Finish()
+	tracer.StartSpan("/orders/1").Finish()
+	tracer.StartSpan("/accounts/1").Finish()
+	span := tracer.StartSpan("/orders/2")
+	span.SetOperationName("/payments/1")
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 5, len(spans))
+	assert.Equal(t, "/users/{id}", spans[0].Name)
+	assert.Equal(t, "/users/{id}", spans[1].Name)
+	assert.Equal(t, "/orders/{id}", spans[2].Name)
+	assert.Empty(t, spans[2].Tags)
+	assert.Equal(t, "overflow", spans[3].Name)
+	assert.Equal(t, map[string]string{OriginalOperationNameKey: "/accounts/1"}, spans[3].Tags)
+	assert.Equal(t, "overflow", spans[4].Name)
+	assert.Equal(t, map[string]string{OriginalOperationNameKey: "/payments/1"}, spans[4].Tags)
+}
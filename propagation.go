@@ -15,13 +15,12 @@
 package zipkintracer
 
 import (
-	"net/http"
+	"fmt"
 	"strings"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/openzipkin/zipkin-go/model"
 	"github.com/openzipkin/zipkin-go/propagation"
-	"github.com/openzipkin/zipkin-go/propagation/b3"
 )
 
 // DelegatingCarrier is a flexible carrier interface which can be implemented
@@ -33,7 +32,8 @@ type DelegatingCarrier interface {
 }
 
 type textMapPropagator struct {
-	tracer *tracerImpl
+	tracer      *tracerImpl
+	propagators []Propagator
 }
 
 func (p *textMapPropagator) Inject(
@@ -48,44 +48,57 @@ func (p *textMapPropagator) Inject(
 	if injector, ok := opaqueCarrier.(propagation.Injector); ok {
 		return injector(model.SpanContext(sc))
 	}
-	// fallback to support native opentracing http carrier
-	if httpCarrier, ok := opaqueCarrier.(opentracing.HTTPHeadersCarrier); ok {
-		req := &http.Request{Header: http.Header(httpCarrier)}
-		switch p.tracer.opts.b3InjectOpt {
-		case B3InjectSingle:
-			return b3.InjectHTTP(req, b3.WithSingleHeaderOnly())(model.SpanContext(sc))
-		case B3InjectBoth:
-			return b3.InjectHTTP(req, b3.WithSingleAndMultiHeader())(model.SpanContext(sc))
-		default:
-			return b3.InjectHTTP(req)(model.SpanContext(sc))
-		}
+
+	// baggage items are injected separately from the trace context headers,
+	// propagators only get the items allowed by the policy
+	bag := newBaggage(sc.Baggage)
+	if policy := p.tracer.opts.baggagePolicy; policy != nil {
+		bag = policy.apply(bag)
+	}
+	sc.Baggage = nil
+	if bag != nil {
+		sc.Baggage = bag
 	}
-	// fallback to support native opentracing textmap writer
-	if carrier, ok := opaqueCarrier.(opentracing.TextMapWriter); ok {
-		var (
-			err error
-			m   = make(b3.Map)
-		)
-		switch p.tracer.opts.b3InjectOpt {
-		case B3InjectSingle:
-			err = m.Inject(b3.WithSingleHeaderOnly())(model.SpanContext(sc))
-		case B3InjectBoth:
-			err = m.Inject(b3.WithSingleAndMultiHeader())(model.SpanContext(sc))
-		default:
-			err = m.Inject()(model.SpanContext(sc))
-		}
 
-		if err != nil {
-			return err
-		}
+	var set func(key, value string)
+	switch carrier := opaqueCarrier.(type) {
+	case opentracing.HTTPHeadersCarrier:
+		// fallback to support native opentracing http carrier
+		set = carrier.Set
+	case opentracing.TextMapWriter:
+		// fallback to support native opentracing textmap writer
+		set = carrier.Set
+	default:
+		return opentracing.ErrInvalidCarrier
+	}
 
-		for k, v := range m {
-			carrier.Set(k, v)
+	var (
+		injected bool
+		firstErr error
+		// contexts holding baggage or a sampling decision only are rejected
+		// by the formats requiring a trace
+		hasTrace = !sc.TraceID.Empty()
+	)
+	for _, propagator := range p.propagators {
+		err := propagator.Inject(model.SpanContext(sc), set)
+		if err == nil {
+			injected = true
+			continue
 		}
-		return nil
+		if firstErr == nil {
+			firstErr = err
+		}
+		if hasTrace {
+			p.tracer.logger.warn("failed to inject trace context",
+				"propagator", fmt.Sprintf("%T", propagator), "error", err)
+		}
+	}
+	if !injected && firstErr != nil && (hasTrace || bag.len() == 0) {
+		return firstErr
 	}
 
-	return opentracing.ErrInvalidCarrier
+	injectBaggage(bag, p.tracer.opts.baggageEncoding, set)
+	return nil
 }
 
 func (p *textMapPropagator) Extract(
@@ -98,31 +111,101 @@ func (p *textMapPropagator) Extract(
 		}
 		return SpanContext{}, err
 	}
-	if httpCarrier, ok := opaqueCarrier.(opentracing.HTTPHeadersCarrier); ok {
-		req := &http.Request{Header: http.Header(httpCarrier)}
-		sc, err := b3.ExtractHTTP(req)()
-		if sc != nil {
-			return SpanContext(*sc), err
-		}
-		return SpanContext{}, err
+
+	reader, ok := opaqueCarrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrUnsupportedFormat
 	}
-	if carrier, ok := opaqueCarrier.(opentracing.TextMapReader); ok {
-		m := make(b3.Map)
-		carrier.ForeachKey(func(key string, val string) error {
-			// no matter the format of the B3 headers, they will be retrieved
-			// using the standard lowercase format e.g. x-b3-traceid. See
-			// https://github.com/openzipkin/zipkin-go/blob/master/propagation/b3/shared.go
-			m[strings.ToLower(key)] = val
-			return nil
-		})
-		sc, err := m.Extract()
-		if sc != nil {
-			return SpanContext(*sc), err
+
+	var (
+		headers = make(Headers)
+		items   = make(map[string]string)
+	)
+	if err := reader.ForeachKey(func(key string, val string) error {
+		// no matter the format of the headers, they will be retrieved using
+		// their lowercase form e.g. x-b3-traceid. See
+		// https://github.com/openzipkin/zipkin-go/blob/master/propagation/b3/shared.go
+		key = strings.ToLower(key)
+		headers.add(key, val)
+		extractBaggage(key, val, items)
+		return nil
+	}); err != nil {
+		p.tracer.logger.warn("failed to read carrier", "error", err)
+	}
+
+	sc, err := p.extractTraceContext(headers)
+	return p.withBaggageItems(sc, err, items)
+}
+
+// extractTraceContext returns the trace context of the first propagator
+// finding one in headers. Contexts holding a trace are preferred over
+// contexts holding a sampling decision only. If no propagator finds a
+// context, the error of the first failing one is returned. Using
+// ExtractAgreement, all propagators are tried and contexts holding different
+// traces or spans are rejected.
+func (p *textMapPropagator) extractTraceContext(headers Headers) (*model.SpanContext, error) {
+	var (
+		result   *model.SpanContext
+		fallback *model.SpanContext
+		firstErr error
+	)
+	for _, propagator := range p.propagators {
+		sc, err := propagator.Extract(headers)
+		switch {
+		case err != nil:
+			if firstErr == nil {
+				firstErr = err
+			}
+		case sc == nil:
+		case sc.TraceID.Empty():
+			if fallback == nil && (sc.Sampled != nil || sc.Debug) {
+				fallback = sc
+			}
+		case result == nil:
+			if p.tracer.opts.extractOpt != ExtractAgreement {
+				return sc, nil
+			}
+			result = sc
+		case result.TraceID != sc.TraceID || result.ID != sc.ID:
+			p.tracer.logger.warn("propagation formats disagree on the trace context",
+				"trace_id", result.TraceID.String(), "span_id", result.ID.String(),
+				"other_trace_id", sc.TraceID.String(), "other_span_id", sc.ID.String())
+			return nil, opentracing.ErrSpanContextCorrupted
 		}
-		return SpanContext{}, err
 	}
+	if result != nil {
+		return result, nil
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, firstErr
+}
 
-	return nil, opentracing.ErrUnsupportedFormat
+// withBaggageItems returns the extracted SpanContext holding the provided
+// baggage items along with the baggage fields set by the propagator, e.g.
+// the W3C tracestate. Baggage is kept even if no trace context headers were
+// found.
+func (p *textMapPropagator) withBaggageItems(
+	sc *model.SpanContext, err error, items map[string]string,
+) (opentracing.SpanContext, error) {
+	if sc == nil {
+		sc = &model.SpanContext{}
+	}
+	bag := newBaggage(sc.Baggage)
+	if len(items) > 0 {
+		bag = (&baggage{items: items}).merge(bag)
+	}
+	sc.Baggage = nil
+	if bag != nil {
+		if policy := p.tracer.opts.baggagePolicy; policy != nil {
+			bag = policy.apply(bag)
+		}
+		if bag != nil {
+			sc.Baggage = bag
+		}
+	}
+	return SpanContext(*sc), err
 }
 
 type accessorPropagator struct {
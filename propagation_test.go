@@ -592,3 +592,88 @@ func TestAccessorPropagator(t *testing.T) {
 		}
 	}
 }
+
+func TestBaggagePropagation(t *testing.T) {
+	encodings := []zipkintracer.BaggageEncoding{
+		zipkintracer.BaggageEncodingOT,
+		zipkintracer.BaggageEncodingW3C,
+		zipkintracer.BaggageEncodingBoth,
+	}
+	for _, encoding := range encodings {
+		zt, _ := zipkin.NewTracer(nil)
+		tracer := zipkintracer.Wrap(zt, zipkintracer.WithBaggageEncoding(encoding))
+
+		sp := tracer.StartSpan("test")
+		sp.SetBaggageItem("user-id", "123")
+		sp.SetBaggageItem("note", "a value, with; separators=")
+
+		carriers := []interface{}{
+			opentracing.HTTPHeadersCarrier{},
+			opentracing.TextMapCarrier{},
+		}
+		for _, carrier := range carriers {
+			if err := tracer.Inject(sp.Context(), opentracing.HTTPHeaders, carrier); err != nil {
+				t.Fatalf("[%d] Inject failed: %+v", encoding, err)
+			}
+
+			otSC, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+			if err != nil {
+				t.Fatalf("[%d] Extract failed: %+v", encoding, err)
+			}
+
+			items := map[string]string{}
+			otSC.ForeachBaggageItem(func(k, v string) bool {
+				items[k] = v
+				return true
+			})
+			want := map[string]string{"user-id": "123", "note": "a value, with; separators="}
+			if !reflect.DeepEqual(want, items) {
+				t.Errorf("[%d] baggage want %+v, have %+v", encoding, want, items)
+			}
+
+			child := tracer.StartSpan("child", opentracing.ChildOf(otSC))
+			if want, have := "123", child.BaggageItem("user-id"); want != have {
+				t.Errorf("[%d] child baggage want %s, have %s", encoding, want, have)
+			}
+		}
+	}
+}
+
+func TestBaggageOnlyExtraction(t *testing.T) {
+	tracer := zipkintracer.Wrap(nil)
+	c := stdHTTP.Header{}
+	c.Set("Ot-Baggage-User-Id", "123")
+	c.Set("Baggage", "tenant=acme;prop=1, region = eu%2Dwest")
+
+	otSC, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(c))
+	if err != nil {
+		t.Fatalf("Extract failed: %+v", err)
+	}
+
+	sc := otSC.(zipkintracer.SpanContext)
+	if !sc.TraceID.Empty() {
+		t.Errorf("TraceID want empty, have %s", sc.TraceID)
+	}
+
+	items := map[string]string{}
+	sc.ForeachBaggageItem(func(k, v string) bool {
+		items[k] = v
+		return true
+	})
+	want := map[string]string{"user-id": "123", "tenant": "acme", "region": "eu-west"}
+	if !reflect.DeepEqual(want, items) {
+		t.Errorf("baggage want %+v, have %+v", want, items)
+	}
+
+	// baggage only contexts can be injected without B3 headers
+	out := stdHTTP.Header{}
+	if err = tracer.Inject(sc, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(out)); err != nil {
+		t.Fatalf("Inject failed: %+v", err)
+	}
+	if want, have := "", out.Get(zb3.TraceID); want != have {
+		t.Errorf("TraceID header want empty, have %s", have)
+	}
+	if want, have := "acme", out.Get("ot-baggage-tenant"); want != have {
+		t.Errorf("baggage header want %s, have %s", want, have)
+	}
+}
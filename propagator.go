@@ -0,0 +1,122 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/propagation/b3"
+)
+
+// Propagator injects and extracts the trace context headers of a format when
+// using the native OpenTracing TextMap and HTTPHeaders carriers, see
+// WithPropagator. Reading and writing the carrier as well as the baggage
+// headers set by WithBaggageEncoding are handled by the tracer.
+type Propagator interface {
+	// Inject writes the headers of sc using set. sc.Baggage holds the baggage
+	// items allowed by the BaggagePolicy.
+	Inject(sc model.SpanContext, set func(key, value string)) error
+	// Extract returns the SpanContext found in headers. It returns a nil
+	// SpanContext and error if none of the headers of the format are found.
+	// Baggage fields of the returned SpanContext are added to the baggage
+	// items found in the baggage headers.
+	Extract(headers Headers) (*model.SpanContext, error)
+}
+
+// Headers holds the values of the headers of a carrier by lowercase key.
+type Headers map[string][]string
+
+// Get returns the first value of the header with the (lowercase) key or an
+// empty string if not found.
+func (h Headers) Get(key string) string {
+	if values := h[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values returns all values of the header with the (lowercase) key.
+func (h Headers) Values(key string) []string {
+	return h[key]
+}
+
+func (h Headers) add(key, value string) {
+	h[key] = append(h[key], value)
+}
+
+// NewB3Propagator returns the Propagator of the B3 headers, injected in the
+// provided style. Extraction accepts both the single and multi header styles.
+func NewB3Propagator(injectOption B3InjectOption) Propagator {
+	return b3Propagator{injectOption: injectOption}
+}
+
+type b3Propagator struct {
+	injectOption B3InjectOption
+}
+
+func (p b3Propagator) Inject(sc model.SpanContext, set func(key, value string)) error {
+	// baggage is injected by the tracer and would be mistaken for a context
+	sc.Baggage = nil
+
+	m := make(b3.Map)
+	var err error
+	switch p.injectOption {
+	case B3InjectSingle:
+		err = m.Inject(b3.WithSingleHeaderOnly())(sc)
+	case B3InjectBoth:
+		err = m.Inject(b3.WithSingleAndMultiHeader())(sc)
+	default:
+		err = m.Inject()(sc)
+	}
+	if err != nil {
+		return err
+	}
+	for k, v := range m {
+		set(k, v)
+	}
+	return nil
+}
+
+func (p b3Propagator) Extract(headers Headers) (*model.SpanContext, error) {
+	m := make(b3.Map)
+	for _, key := range []string{b3.TraceID, b3.SpanID, b3.ParentSpanID, b3.Sampled, b3.Flags, b3.Context} {
+		if value := headers.Get(key); value != "" {
+			m[key] = value
+		}
+	}
+	return m.Extract()
+}
+
+// textMapPropagators returns the propagators registered by WithPropagator or
+// the ones of the formats set by WithPropagationFormat, in order.
+func (opts *TracerOptions) textMapPropagators() []Propagator {
+	if len(opts.propagators) > 0 {
+		return opts.propagators
+	}
+	format := opts.propagationFormat
+	if format == 0 {
+		format = PropagationB3
+	}
+	var propagators []Propagator
+	if format&PropagationB3 != 0 {
+		propagators = append(propagators, NewB3Propagator(opts.b3InjectOpt))
+	}
+	if format&PropagationW3C != 0 {
+		propagators = append(propagators, NewW3CPropagator())
+	}
+	if format&PropagationJaeger != 0 {
+		propagators = append(propagators, NewJaegerPropagator())
+	}
+	return propagators
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantPropagator is a user defined format carrying the trace ID in decimal
+// along with a tenant baggage item.
+type tenantPropagator struct{}
+
+func (tenantPropagator) Inject(sc model.SpanContext, set func(key, value string)) error {
+	if sc.TraceID.Empty() {
+		return opentracing.ErrInvalidSpanContext
+	}
+	set("x-tenant-trace", strconv.FormatUint(sc.TraceID.Low, 10)+"."+strconv.FormatUint(uint64(sc.ID), 10))
+	return nil
+}
+
+func (tenantPropagator) Extract(headers Headers) (*model.SpanContext, error) {
+	header := headers.Get("x-tenant-trace")
+	if header == "" {
+		return nil, nil
+	}
+	t, s, _ := strings.Cut(header, ".")
+	traceID, _ := strconv.ParseUint(t, 10, 64)
+	id, _ := strconv.ParseUint(s, 10, 64)
+	if traceID == 0 || id == 0 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	return &model.SpanContext{
+		TraceID: model.TraceID{Low: traceID},
+		ID:      model.ID(id),
+		Baggage: &baggage{items: map[string]string{"tenant": headers.Get("x-tenant")}},
+	}, nil
+}
+
+func TestMultiFormatInjection(t *testing.T) {
+	tracer := Wrap(mustTracer(t),
+		WithPropagator(NewB3Propagator(B3InjectStandard)),
+		WithPropagator(NewB3Propagator(B3InjectSingle)),
+		WithPropagator(NewW3CPropagator()),
+		WithPropagator(tenantPropagator{}),
+	)
+
+	span := tracer.StartSpan("x")
+	span.SetBaggageItem("user", "alice")
+	sc := span.Context().(SpanContext)
+
+	carrier := opentracing.HTTPHeadersCarrier(http.Header{})
+	assert.NoError(t, tracer.Inject(span.Context(), opentracing.HTTPHeaders, carrier))
+	header := http.Header(carrier)
+	assert.Equal(t, sc.TraceID.String(), header.Get("X-B3-TraceId"))
+	assert.Equal(t, sc.TraceID.String()+"-"+sc.ID.String()+"-1", header.Get("B3"))
+	assert.Equal(t, "00-0000000000000000"+sc.TraceID.String()+"-"+sc.ID.String()+"-01", header.Get("Traceparent"))
+	assert.Equal(t, strconv.FormatUint(sc.TraceID.Low, 10)+"."+strconv.FormatUint(uint64(sc.ID), 10), header.Get("X-Tenant-Trace"))
+	assert.Equal(t, "alice", header.Get("Ot-Baggage-User"))
+}
+
+func TestOrderedExtraction(t *testing.T) {
+	tracer := Wrap(mustTracer(t),
+		WithPropagator(tenantPropagator{}),
+		WithPropagator(NewB3Propagator(B3InjectStandard)),
+	)
+
+	// the first propagator finding a context wins
+	sc, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		"x-tenant-trace":  "10.20",
+		"x-tenant":        "acme",
+		"x-b3-traceid":    "000000000000001e",
+		"x-b3-spanid":     "0000000000000028",
+		"ot-baggage-user": "alice",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, model.TraceID{Low: 10}, sc.(SpanContext).TraceID)
+	bag := sc.(SpanContext).Baggage.(*baggage)
+	assert.Equal(t, "acme", bag.item("tenant"))
+	assert.Equal(t, "alice", bag.item("user"))
+
+	// corrupted contexts are skipped
+	sc, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		"x-tenant-trace": "invalid",
+		"x-b3-traceid":   "000000000000001e",
+		"x-b3-spanid":    "0000000000000028",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, model.TraceID{Low: 30}, sc.(SpanContext).TraceID)
+
+	_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		"x-tenant-trace": "invalid",
+	})
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+}
+
+func TestExtractAgreement(t *testing.T) {
+	tracer := Wrap(mustTracer(t),
+		WithPropagationFormat(PropagationB3|PropagationW3C|PropagationJaeger),
+		WithExtractOption(ExtractAgreement),
+	)
+
+	carrier := opentracing.TextMapCarrier{
+		"x-b3-traceid":  "4bf92f3577b34da6a3ce929d0e0e4736",
+		"x-b3-spanid":   "00f067aa0ba902b7",
+		"x-b3-sampled":  "1",
+		"traceparent":   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"uber-trace-id": "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+	}
+	sc, err := tracer.Extract(opentracing.TextMap, carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.(SpanContext).TraceID.String())
+
+	carrier["uber-trace-id"] = "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b8:0:1"
+	_, err = tracer.Extract(opentracing.TextMap, carrier)
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+
+	// without agreement check the first context is used
+	tracer = Wrap(mustTracer(t), WithPropagationFormat(PropagationB3|PropagationW3C|PropagationJaeger))
+	sc, err = tracer.Extract(opentracing.TextMap, carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, model.ID(0x00f067aa0ba902b7), sc.(SpanContext).ID)
+}
+
+type failingPropagator struct{}
+
+func (failingPropagator) Inject(model.SpanContext, func(key, value string)) error {
+	return errors.New("inject failed")
+}
+
+func (failingPropagator) Extract(Headers) (*model.SpanContext, error) {
+	return nil, nil
+}
+
+func TestInjectErrors(t *testing.T) {
+	logger := &recordingLogger{}
+	tracer := Wrap(mustTracer(t),
+		WithLogger(logger),
+		WithPropagator(NewB3Propagator(B3InjectStandard)),
+		WithPropagator(failingPropagator{}),
+	)
+	span := tracer.StartSpan("x")
+	span.SetBaggageItem("user", "alice")
+
+	// errors are logged even if another propagator succeeds
+	carrier := opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, carrier))
+	assert.Equal(t, []string{"failed to inject trace context"}, logger.messages)
+	assert.Equal(t, []interface{}{"propagator", "zipkintracer.failingPropagator", "error", errors.New("inject failed")}, logger.keyvals[0])
+
+	// baggage does not hide a trace context which could not be injected
+	tracer = Wrap(mustTracer(t), WithPropagator(failingPropagator{}))
+	span = tracer.StartSpan("x")
+	span.SetBaggageItem("user", "alice")
+	assert.Error(t, tracer.Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier{}))
+
+	// contexts holding baggage only are injected without trace context
+	tracer = Wrap(mustTracer(t), WithLogger(logger), WithPropagationFormat(PropagationW3C))
+	sc := SpanContext{Baggage: newBaggage(nil).withItem("user", "alice")}
+	carrier = opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(sc, opentracing.TextMap, carrier))
+	assert.Equal(t, "alice", carrier["ot-baggage-user"])
+	assert.Equal(t, 1, len(logger.messages))
+}
@@ -0,0 +1,187 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// DefaultMaxSampledOperations is the default number of distinct operation
+// names an OperationRateLimitingSampler guarantees a rate for.
+const DefaultMaxSampledOperations = 2000
+
+// OperationRateLimitingSampler samples at least a minimum number of traces
+// per second per operation name, so low volume operations are always
+// visible, and caps the number of traces sampled per second in total, so
+// high volume operations cannot flood Zipkin. Traces exceeding the per
+// operation rate are sampled with the fallback sampling rate within the total
+// rate, see WithFallbackSamplingRate. The per operation rate takes precedence
+// over the total rate. As the sampler decides on every root span, the sampler
+// of the zipkin tracer is not used.
+//
+// Use its Sample method as TagSampler:
+//
+//	sampler := NewOperationRateLimitingSampler(1, 100)
+//	tracer := Wrap(tr, WithTagSampler(sampler.Sample))
+type OperationRateLimitingSampler struct {
+	minPerOperation float64
+	maxOperations   int
+	fallbackRate    float64
+	now             func() time.Time
+
+	total *rateLimiter
+
+	mtx        sync.RWMutex
+	operations map[string]*rateLimiter
+}
+
+// RateLimitingSamplerOption allows for functional options of the
+// OperationRateLimitingSampler.
+type RateLimitingSamplerOption func(s *OperationRateLimitingSampler)
+
+// WithSamplerClock sets the clock of the sampler. It defaults to time.Now.
+func WithSamplerClock(now func() time.Time) RateLimitingSamplerOption {
+	return func(s *OperationRateLimitingSampler) {
+		s.now = now
+	}
+}
+
+// WithMaxSampledOperations sets the number of distinct operation names the
+// per operation rate is guaranteed for. Other operations are only subject to
+// the total rate. It defaults to DefaultMaxSampledOperations.
+func WithMaxSampledOperations(maxOperations int) RateLimitingSamplerOption {
+	return func(s *OperationRateLimitingSampler) {
+		s.maxOperations = maxOperations
+	}
+}
+
+// WithFallbackSamplingRate sets the probability, between 0 and 1, traces
+// exceeding the per operation rate are sampled with. Only sampled traces
+// count towards the total rate. It defaults to 1, sampling up to the total
+// rate.
+func WithFallbackSamplingRate(rate float64) RateLimitingSamplerOption {
+	return func(s *OperationRateLimitingSampler) {
+		s.fallbackRate = rate
+	}
+}
+
+// NewOperationRateLimitingSampler returns a sampler sampling at least
+// minPerOperation traces per second per operation name and at most maxTotal
+// traces per second in total. A maxTotal of zero disables the total cap.
+func NewOperationRateLimitingSampler(
+	minPerOperation, maxTotal float64, opts ...RateLimitingSamplerOption,
+) *OperationRateLimitingSampler {
+	s := &OperationRateLimitingSampler{
+		minPerOperation: minPerOperation,
+		maxOperations:   DefaultMaxSampledOperations,
+		fallbackRate:    1,
+		now:             time.Now,
+		operations:      make(map[string]*rateLimiter),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if maxTotal > 0 {
+		s.total = newRateLimiter(maxTotal, s.now())
+	}
+	return s
+}
+
+// Sample implements TagSampler.
+func (s *OperationRateLimitingSampler) Sample(operationName string, _ opentracing.Tags) SamplingDecision {
+	now := s.now()
+	if l := s.operation(operationName, now); l != nil && l.allow(now) {
+		if s.total != nil {
+			// guaranteed traces count towards the total rate
+			s.total.allow(now)
+		}
+		return SamplingSample
+	}
+	if s.fallbackRate <= 0 || (s.fallbackRate < 1 && rand.Float64() >= s.fallbackRate) {
+		return SamplingDrop
+	}
+	if s.total != nil && !s.total.allow(now) {
+		return SamplingDrop
+	}
+	return SamplingSample
+}
+
+// operation returns the rate limiter of the operation or nil if the sampler
+// does not guarantee a rate for it.
+func (s *OperationRateLimitingSampler) operation(name string, now time.Time) *rateLimiter {
+	if s.minPerOperation <= 0 {
+		return nil
+	}
+	s.mtx.RLock()
+	l, ok := s.operations[name]
+	s.mtx.RUnlock()
+	if ok {
+		return l
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if l, ok = s.operations[name]; ok {
+		return l
+	}
+	if len(s.operations) >= s.maxOperations {
+		return nil
+	}
+	l = newRateLimiter(s.minPerOperation, now)
+	s.operations[name] = l
+	return l
+}
+
+// rateLimiter is a token bucket allowing rate events per second with bursts
+// of up to one second worth of events.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mtx     sync.Mutex
+	balance float64
+	last    time.Time
+}
+
+func newRateLimiter(rate float64, now time.Time) *rateLimiter {
+	burst := math.Max(rate, 1)
+	return &rateLimiter{
+		rate:    rate,
+		burst:   burst,
+		balance: burst,
+		last:    now,
+	}
+}
+
+// allow reports whether an event is allowed at the provided time.
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.balance = math.Min(l.burst, l.balance+elapsed.Seconds()*l.rate)
+		l.last = now
+	}
+	if l.balance < 1 {
+		return false
+	}
+	l.balance--
+	return true
+}
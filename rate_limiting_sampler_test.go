@@ -0,0 +1,103 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationRateLimitingSampler(t *testing.T) {
+	now := time.Unix(1665000000, 0)
+	clock := func() time.Time { return now }
+	s := NewOperationRateLimitingSampler(2, 5,
+		WithSamplerClock(clock),
+		WithMaxSampledOperations(3),
+	)
+
+	decisions := func(operationName string, n int) map[SamplingDecision]int {
+		d := make(map[SamplingDecision]int)
+		for i := 0; i < n; i++ {
+			d[s.Sample(operationName, nil)]++
+		}
+		return d
+	}
+
+	// the per operation rate is guaranteed and counts towards the total rate
+	assert.Equal(t, map[SamplingDecision]int{SamplingSample: 5, SamplingDrop: 5}, decisions("a", 10))
+	assert.Equal(t, map[SamplingDecision]int{SamplingSample: 2, SamplingDrop: 8}, decisions("b", 10))
+
+	// the total rate refills over time
+	now = now.Add(200 * time.Millisecond)
+	assert.Equal(t, map[SamplingDecision]int{SamplingSample: 1, SamplingDrop: 9}, decisions("a", 10))
+
+	// untracked operations are subject to the total rate only
+	now = now.Add(time.Second)
+	decisions("c", 1)
+	assert.Equal(t, map[SamplingDecision]int{SamplingSample: 4, SamplingDrop: 6}, decisions("d", 10))
+}
+
+func TestOperationRateLimitingSamplerFallbackRate(t *testing.T) {
+	now := time.Unix(1665000000, 0)
+	s := NewOperationRateLimitingSampler(1, 2,
+		WithSamplerClock(func() time.Time { return now }),
+		WithFallbackSamplingRate(0),
+		WithMaxSampledOperations(2),
+	)
+
+	// traces exceeding the per operation rate are dropped
+	assert.Equal(t, SamplingSample, s.Sample("a", nil))
+	assert.Equal(t, SamplingDrop, s.Sample("a", nil))
+	assert.Equal(t, SamplingDrop, s.Sample("a", nil))
+	assert.Equal(t, SamplingSample, s.Sample("b", nil))
+	assert.Equal(t, SamplingDrop, s.Sample("c", nil))
+}
+
+func TestOperationRateLimitingSamplerUnlimitedTotal(t *testing.T) {
+	now := time.Unix(1665000000, 0)
+	s := NewOperationRateLimitingSampler(1, 0,
+		WithSamplerClock(func() time.Time { return now }),
+		WithFallbackSamplingRate(0),
+	)
+
+	assert.Equal(t, SamplingSample, s.Sample("a", nil))
+	assert.Equal(t, SamplingDrop, s.Sample("a", nil))
+	now = now.Add(time.Second)
+	assert.Equal(t, SamplingSample, s.Sample("a", nil))
+}
+
+func TestOperationRateLimitingSamplerTracer(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec, zipkin.WithSampler(zipkin.NeverSample))
+	sampler := NewOperationRateLimitingSampler(1, 10, WithFallbackSamplingRate(0))
+	tracer := Wrap(tr,
+		WithTagSampler(sampler.Sample),
+		WithOperationNameNormalizer(DefaultOperationNameNormalizer),
+	)
+
+	for i := 0; i < 5; i++ {
+		tracer.StartSpan("a").Finish()
+		tracer.StartSpan("b").Finish()
+		// normalized names share a rate
+		tracer.StartSpan("/users/" + strconv.Itoa(i)).Finish()
+	}
+
+	assert.Equal(t, 3, len(rec.Flush()))
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// SamplingDecision is the decision of a TagSampler.
+type SamplingDecision int
+
+// Available SamplingDecision values
+const (
+	// SamplingDefer leaves the decision to the sampler of the zipkin tracer.
+	SamplingDefer SamplingDecision = iota
+	// SamplingSample samples the trace.
+	SamplingSample
+	// SamplingDrop does not sample the trace.
+	SamplingDrop
+)
+
+// TagSampler decides whether a new trace is sampled based on the operation
+// name and tags its root span is started with, e.g. to always sample
+// requests to /checkout or to never sample health checks. The operation name
+// is the one recorded by the span, see WithOperationNameNormalizer.
+type TagSampler func(operationName string, tags opentracing.Tags) SamplingDecision
+
+// sample applies the decision of the configured TagSampler to the parent of
+// a new span. Spans with a parent trace or a sampling decision provided by
+// the parent or the sampling.priority tag are left unchanged.
+func (t *tracerImpl) sample(
+	parent *SpanContext, operationName string, tags opentracing.Tags,
+) *SpanContext {
+	if t.opts.tagSampler == nil {
+		return parent
+	}
+	if parent != nil && (!parent.TraceID.Empty() || parent.Debug || parent.Sampled != nil) {
+		return parent
+	}
+
+	var sampled bool
+	switch t.opts.tagSampler(operationName, tags) {
+	case SamplingSample:
+		sampled = true
+	case SamplingDrop:
+		sampled = false
+	default:
+		return parent
+	}
+
+	if parent == nil {
+		parent = &SpanContext{}
+	}
+	parent.Sampled = &sampled
+	return parent
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"strings"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagSampler(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec, zipkin.WithSampler(zipkin.NeverSample))
+	tracer := Wrap(tr, WithTagSampler(func(operationName string, tags opentracing.Tags) SamplingDecision {
+		if operationName == "health" {
+			return SamplingDrop
+		}
+		if url, ok := tags[string(ext.HTTPUrl)].(string); ok && strings.HasPrefix(url, "/checkout") {
+			return SamplingSample
+		}
+		return SamplingDefer
+	}))
+
+	checkout := tracer.StartSpan("post", opentracing.Tag{Key: string(ext.HTTPUrl), Value: "/checkout/42"})
+	child := tracer.StartSpan("child", opentracing.ChildOf(checkout.Context()))
+	child.Finish()
+	checkout.Finish()
+	tracer.StartSpan("get", opentracing.Tag{Key: string(ext.HTTPUrl), Value: "/cart"}).Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, "post", spans[1].Name)
+	assert.False(t, spans[1].Debug)
+
+	// the sampling.priority tag takes precedence
+	tracer.StartSpan("health", opentracing.Tag{Key: string(ext.SamplingPriority), Value: 1}).Finish()
+	tracer.StartSpan("health").Finish()
+
+	spans = rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.True(t, spans[0].Debug)
+}
@@ -0,0 +1,182 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Sanitizer rewrites data before it is recorded on a span, e.g. to redact
+// personally identifiable information.
+type Sanitizer interface {
+	// Tag returns the value to record for a tag.
+	Tag(key, value string) string
+	// LogField returns the value to record for a log field.
+	LogField(key, value string) string
+	// OperationName returns the operation name to record.
+	OperationName(name string) string
+}
+
+// RedactedMask replaces data redacted by the Sanitizer returned by
+// NewRedactor.
+const RedactedMask = "[REDACTED]"
+
+var (
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	bearerPattern      = regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9\-._~+/]+=*`)
+	cardNumberPattern  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+	cardNumberReplacer = strings.NewReplacer(" ", "", "-", "")
+)
+
+type redactor struct {
+	sensitiveKeys map[string]struct{}
+}
+
+// NewRedactor returns a Sanitizer masking email addresses, bearer tokens and
+// credit card numbers in tags, log fields and operation names. The complete
+// value of tags and log fields with one of the provided keys, e.g. "password"
+// or "authorization", is masked as well. Keys are matched case-insensitively.
+func NewRedactor(sensitiveKeys ...string) Sanitizer {
+	r := &redactor{sensitiveKeys: make(map[string]struct{}, len(sensitiveKeys))}
+	for _, key := range sensitiveKeys {
+		r.sensitiveKeys[strings.ToLower(key)] = struct{}{}
+	}
+	return r
+}
+
+func (r *redactor) Tag(key, value string) string {
+	if _, ok := r.sensitiveKeys[strings.ToLower(key)]; ok {
+		return RedactedMask
+	}
+	return r.redact(value)
+}
+
+func (r *redactor) LogField(key, value string) string {
+	return r.Tag(key, value)
+}
+
+func (r *redactor) OperationName(name string) string {
+	return r.redact(name)
+}
+
+func (r *redactor) redact(value string) string {
+	if strings.IndexByte(value, '@') >= 0 {
+		value = emailPattern.ReplaceAllString(value, RedactedMask)
+	}
+	value = bearerPattern.ReplaceAllString(value, "$1 "+RedactedMask)
+	value = cardNumberPattern.ReplaceAllStringFunc(value, func(digits string) string {
+		if luhnValid(cardNumberReplacer.Replace(digits)) {
+			return RedactedMask
+		}
+		return digits
+	})
+	return value
+}
+
+// luhnValid reports whether the digits pass the Luhn checksum used by credit
+// card numbers, limiting false positives on other long numbers.
+func luhnValid(digits string) bool {
+	var sum int
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// sanitizeLogFields returns the fields with their values sanitized. Lazy
+// loggers are resolved so their fields can be sanitized as well.
+func sanitizeLogFields(sanitizer Sanitizer, fields []log.Field) []log.Field {
+	sanitized := make([]log.Field, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := field.Value().(log.LazyLogger); ok {
+			for _, kv := range collectLogFields([]log.Field{field}) {
+				sanitized = append(sanitized, sanitizeLogField(sanitizer, log.Object(kv.key, kv.value)))
+			}
+			continue
+		}
+		sanitized = append(sanitized, sanitizeLogField(sanitizer, field))
+	}
+	return sanitized
+}
+
+func sanitizeLogField(sanitizer Sanitizer, field log.Field) log.Field {
+	value, ok := field.Value().(string)
+	if !ok {
+		value = fmt.Sprint(field.Value())
+	}
+	if sanitizedValue := sanitizer.LogField(field.Key(), value); sanitizedValue != value {
+		return log.String(field.Key(), sanitizedValue)
+	}
+	return field
+}
+
+// sanitizeTag returns the tag value to record using the configured Sanitizer.
+func (opts *TracerOptions) sanitizeTag(key, value string) string {
+	if opts.sanitizer == nil {
+		return value
+	}
+	return opts.sanitizer.Tag(key, value)
+}
+
+// sanitizeTags returns the tags to record using the configured Sanitizer.
+func (opts *TracerOptions) sanitizeTags(tags map[string]string) map[string]string {
+	if opts.sanitizer == nil {
+		return tags
+	}
+	for k, v := range tags {
+		tags[k] = opts.sanitizer.Tag(k, v)
+	}
+	return tags
+}
+
+// sanitizeLogFields returns the log fields to record using the configured
+// Sanitizer.
+func (opts *TracerOptions) sanitizeLogFields(fields []log.Field) []log.Field {
+	if opts.sanitizer == nil {
+		return fields
+	}
+	return sanitizeLogFields(opts.sanitizer, fields)
+}
+
+// sanitizeLogValue returns the value of a log field to record using the
+// configured Sanitizer.
+func (opts *TracerOptions) sanitizeLogValue(key, value string) string {
+	if opts.sanitizer == nil {
+		return value
+	}
+	return opts.sanitizer.LogField(key, value)
+}
+
+// sanitizeOperationName returns the operation name to record using the
+// configured Sanitizer.
+func (opts *TracerOptions) sanitizeOperationName(name string) string {
+	if opts.sanitizer == nil {
+		return name
+	}
+	return opts.sanitizer.OperationName(name)
+}
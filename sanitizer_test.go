@@ -0,0 +1,93 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"errors"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor("Password", "authorization")
+
+	testCases := []struct {
+		key      string
+		value    string
+		expected string
+	}{
+		{"user", "mail john.doe+x@example.co.uk now", "mail [REDACTED] now"},
+		{"header", "Bearer eyJhbGciOi.eyJzdWIi.SflKxw==", "Bearer [REDACTED]"},
+		{"card", "paid with 4111 1111 1111 1111.", "paid with [REDACTED]."},
+		{"card", "4111-1111-1111-1111", "[REDACTED]"},
+		{"timestamp", "1665999999123456789", "1665999999123456789"},
+		{"password", "hunter2", "[REDACTED]"},
+		{"AUTHORIZATION", "Basic dXNlcjpwYXNz", "[REDACTED]"},
+		{"http.url", "/users/42", "/users/42"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, r.Tag(tc.key, tc.value), tc.value)
+		assert.Equal(t, tc.expected, r.LogField(tc.key, tc.value), tc.value)
+	}
+
+	assert.Equal(t, "password", r.OperationName("password"))
+	assert.Equal(t, "notify [REDACTED]", r.OperationName("notify jane@example.com"))
+}
+
+func TestSpan_Sanitizer(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithSanitizer(NewRedactor("password")))
+
+	span := tracer.StartSpan("login jane@example.com", opentracing.Tags{
+		"password": "hunter2",
+		"user":     "jane@example.com",
+	})
+	span.SetOperationName("login jane@example.com")
+	span.SetTag("card", int64(4111111111111111))
+	span.LogKV("password", "hunter2", "count", 3)
+	span.LogFields(log.Lazy(func(fv log.Encoder) {
+		fv.EmitString("email", "jane@example.com")
+	}))
+	span.LogEventWithPayload("auth", "Bearer abc.def")
+	span.SetTag("error", errors.New("unknown user jane@example.com"))
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "login [REDACTED]", spans[0].Name)
+	assert.Equal(t, map[string]string{
+		"password": "[REDACTED]",
+		"user":     "[REDACTED]",
+		"card":     "[REDACTED]",
+		"error":    "unknown user [REDACTED]",
+	}, spans[0].Tags)
+
+	var annotations []string
+	for _, a := range spans[0].Annotations {
+		annotations = append(annotations, a.Value)
+	}
+	assert.Equal(t, []string{
+		"password:[REDACTED]",
+		"count:3",
+		"email:[REDACTED]",
+		"auth:Bearer [REDACTED]",
+	}, annotations)
+}
@@ -16,6 +16,8 @@ package zipkintracer
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	otobserver "github.com/opentracing-contrib/go-observer"
@@ -23,9 +25,12 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
 	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
 )
 
 // FinisherWithDuration allows to finish span with given duration
+//
+// Deprecated: zipkin.Span implements FinishedWithDuration.
 type FinisherWithDuration interface {
 	FinishedWithDuration(d time.Duration)
 }
@@ -35,51 +40,235 @@ type spanImpl struct {
 	zipkinSpan zipkin.Span
 	startTime  time.Time
 	observer   otobserver.SpanObserver
+
+	mtx     sync.RWMutex
+	baggage *baggage
+
+	// span.kind and peer.* tags set after span creation are applied to the
+	// zipkin span on finish
+	kind            model.Kind
+	remoteEndpoint  model.Endpoint
+	kindChanged     bool
+	endpointChanged bool
+
+	// sampling decision of a root span forced by a sampling.priority tag set
+	// after span creation
+	root           bool
+	forcedSampling *bool
+
+	finished     int32
+	leakReported int32
+	// set while the span is counted as in-flight
+	tracked int32
+
+	// spans wrapped by FromZipkinSpan are reported by zipkin-go on finish if
+	// they were sampled on creation
+	selfReporting bool
+
+	limiter *spanLimiter
+
+	// the error tag is set on finish
+	errored      bool
+	errorMessage string
+
+	// passed to the finish decorators
+	operationName string
+	startOptions  opentracing.StartSpanOptions
+}
+
+// decoratorSpan is the zipkin span handed to span decorators. Tags and
+// annotations are recorded as if set through the OpenTracing span.
+type decoratorSpan struct {
+	zipkin.Span
+	span *spanImpl
+}
+
+func (d decoratorSpan) Tag(key, value string) {
+	d.span.tag(key, value)
+}
+
+func (d decoratorSpan) Annotate(t time.Time, value string) {
+	d.span.annotate(t, value)
 }
 
 func (s *spanImpl) SetOperationName(operationName string) opentracing.Span {
 	if s.observer != nil {
+		s.tracer.stats.ObserverCallbacks.inc()
 		s.observer.OnSetOperationName(operationName)
 	}
 
-	s.zipkinSpan.SetName(operationName)
+	s.mtx.Lock()
+	s.operationName = operationName
+	s.mtx.Unlock()
+
+	name, overflowed := s.tracer.operationName(operationName)
+	s.zipkinSpan.SetName(name)
+	if overflowed {
+		s.tracer.logger.warn("operation name exceeds the operation name limit",
+			"operation", s.tracer.opts.sanitizeOperationName(operationName))
+		s.tag(OriginalOperationNameKey, operationName)
+	}
 	return s
 }
 
 func (s *spanImpl) SetTag(key string, value interface{}) opentracing.Span {
 	if s.observer != nil {
+		s.tracer.stats.ObserverCallbacks.inc()
 		s.observer.OnSetTag(key, value)
 	}
 
 	if key == string(ext.SamplingPriority) {
-		// there are no means for now to change the sampling decision
-		// but when finishedSpanHandler is in place we could change this.
+		s.setSamplingPriority(value)
 		return s
 	}
 
-	if key == string(ext.SpanKind) ||
-		key == string(ext.PeerService) ||
-		key == string(ext.PeerHostIPv4) ||
-		key == string(ext.PeerHostIPv6) ||
-		key == string(ext.PeerPort) {
-		// this tags are translated into kind and remoteEndpoint which can
-		// only be set on span creation
+	if key == string(ext.Error) {
+		message, errored := parseErrorTag(value)
+		s.setError(message, errored)
 		return s
 	}
 
-	s.zipkinSpan.Tag(key, fmt.Sprint(value))
+	if s.setPeerTag(key, value) {
+		// this tags are translated into kind and remoteEndpoint which are
+		// applied on finish
+		return s
+	}
+	if key == string(ext.SpanKind) || isStrictPeerTag(key) {
+		s.tracer.logger.warn("unsupported tag value", "key", key, "type", fmt.Sprintf("%T", value))
+	}
+
+	s.tag(key, s.tracer.opts.encodeTag(key, value))
 	return s
 }
 
+// tag sets a sanitized tag on the zipkin span within the configured
+// SpanLimits.
+func (s *spanImpl) tag(key, value string) {
+	value, ok := s.limiter.tag(key, s.tracer.opts.sanitizeTag(key, value))
+	if !ok {
+		s.tracer.logger.warn("dropped tag exceeding span limits", "key", key)
+		return
+	}
+	s.zipkinSpan.Tag(key, value)
+}
+
+// annotate adds an annotation to the zipkin span within the configured
+// SpanLimits.
+func (s *spanImpl) annotate(t time.Time, value string) {
+	value, ok := s.limiter.annotation(value)
+	if !ok {
+		s.tracer.logger.warn("dropped annotation exceeding span limits")
+		return
+	}
+	s.zipkinSpan.Annotate(t, value)
+}
+
+// setSamplingPriority forces the sampling decision of root spans. Spans
+// with a parent and noop spans follow the decision of their trace. A decision
+// differing from the one made on creation is reported through the reporter
+// set by WithReporter.
+func (s *spanImpl) setSamplingPriority(value interface{}) {
+	priority, ok := parseInt(value)
+	if !ok {
+		s.tracer.logger.warn("unsupported sampling.priority tag value", "type", fmt.Sprintf("%T", value))
+		return
+	}
+	if !s.root {
+		return
+	}
+	if spanModel(s.zipkinSpan) == nil {
+		// noop spans are never reported, advertising the decision would make
+		// downstream services sample a trace without root
+		s.tracer.logger.warn("sampling.priority can not force the sampling of a noop span")
+		return
+	}
+	sampled := priority > 0
+
+	s.mtx.Lock()
+	s.forcedSampling = &sampled
+	s.mtx.Unlock()
+}
+
+// setError records whether the span resulted in an error. A known error
+// message is kept unless a new one is provided.
+func (s *spanImpl) setError(message string, errored bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.errored = errored
+	if !errored {
+		s.errorMessage = ""
+	} else if message != "" {
+		s.errorMessage = message
+	}
+}
+
+// applyError sets the Zipkin error tag if the span resulted in an error.
+func (s *spanImpl) applyError() {
+	s.mtx.RLock()
+	errored, message := s.errored, s.errorMessage
+	s.mtx.RUnlock()
+
+	if !errored {
+		return
+	}
+	if message == "" {
+		message = defaultErrorMessage
+	}
+	s.zipkinSpan.Tag(string(ext.Error), s.tracer.opts.sanitizeTag(string(ext.Error), message))
+}
+
+func (s *spanImpl) setPeerTag(key string, value interface{}) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if key == string(ext.SpanKind) {
+		kind, ok := parseKind(value)
+		if ok {
+			s.kind = kind
+			s.kindChanged = true
+		}
+		return ok
+	}
+
+	remoteEndpoint := s.remoteEndpoint
+	if !setPeerTag(&remoteEndpoint, key, value) {
+		return false
+	}
+	s.remoteEndpoint = remoteEndpoint
+	s.endpointChanged = true
+	return true
+}
+
+// applyPeerTags applies the span.kind and peer.* tags set after span creation
+// to the zipkin span.
+func (s *spanImpl) applyPeerTags() {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.endpointChanged {
+		remoteEndpoint := s.remoteEndpoint
+		s.zipkinSpan.SetRemoteEndpoint(&remoteEndpoint)
+	}
+	if s.kindChanged {
+		// zipkin.Span has no kind setter and the mutex of the zipkin span is
+		// not accessible. Writing the kind is safe nonetheless: it runs once
+		// on finish, the zipkin-go setters do not touch the kind and the
+		// model is only read by the reporter after the span is finished.
+		if m := spanModel(s.zipkinSpan); m != nil {
+			m.Kind = s.kind
+		}
+	}
+}
+
 func (s *spanImpl) LogKV(keyValues ...interface{}) {
 	fields, err := log.InterleavedKVToFields(keyValues...)
 	if err != nil {
+		s.tracer.logger.warn("dropped invalid LogKV key values", "error", err)
 		return
 	}
 
-	for _, field := range fields {
-		s.zipkinSpan.Annotate(time.Now(), field.String())
-	}
+	s.logFields(time.Now(), fields...)
 }
 
 func (s *spanImpl) LogFields(fields ...log.Field) {
@@ -87,8 +276,28 @@ func (s *spanImpl) LogFields(fields ...log.Field) {
 }
 
 func (s *spanImpl) logFields(t time.Time, fields ...log.Field) {
+	if isErrorLog(fields) {
+		s.setError(errorLogMessage(fields), true)
+		if s.tracer.opts.errorLogOpt == ErrorLogTags {
+			for _, field := range fields {
+				if field.Key() == logFieldEvent {
+					continue
+				}
+				s.tag(field.Key(), s.tracer.opts.encodeTag(field.Key(), field.Value()))
+			}
+			return
+		}
+	}
+
+	fields = s.tracer.opts.sanitizeLogFields(fields)
+	fields = truncateLogFields(fields, s.tracer.opts.maxLogValueLen)
+	if s.tracer.opts.logEncoder != nil {
+		s.annotate(t, s.tracer.opts.logEncoder(fields))
+		return
+	}
+
 	for _, field := range fields {
-		s.zipkinSpan.Annotate(t, field.String())
+		s.annotate(t, field.String())
 	}
 }
 
@@ -110,36 +319,184 @@ func (s *spanImpl) Log(ld opentracing.LogData) {
 		ld.Timestamp = time.Now()
 	}
 
-	s.zipkinSpan.Annotate(ld.Timestamp, fmt.Sprintf("%s:%s", ld.Event, ld.Payload))
-}
+	if ld.Event == errorEvent {
+		s.logFields(ld.Timestamp, log.String(logFieldEvent, ld.Event), log.Object(logFieldErrorObject, ld.Payload))
+		return
+	}
 
-func (s *spanImpl) Finish() {
-	if s.observer != nil {
-		s.observer.OnFinish(opentracing.FinishOptions{})
+	if s.tracer.opts.logEncoder != nil {
+		s.logFields(ld.Timestamp, ld.ToLogRecord().Fields...)
+		return
 	}
 
-	s.zipkinSpan.Finish()
+	if ld.Payload == nil {
+		s.annotate(ld.Timestamp, s.tracer.opts.sanitizeLogValue(logFieldEvent, ld.Event))
+		return
+	}
+	value := s.tracer.opts.sanitizeLogValue(logFieldPayload, fmt.Sprint(ld.Payload))
+	value = truncate(value, s.tracer.opts.maxLogValueLen)
+	s.annotate(ld.Timestamp, s.tracer.opts.sanitizeLogValue(logFieldEvent, ld.Event)+":"+value)
+}
+
+func (s *spanImpl) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
 }
 
 func (s *spanImpl) FinishWithOptions(opts opentracing.FinishOptions) {
 	if s.observer != nil {
+		s.tracer.stats.ObserverCallbacks.inc()
 		s.observer.OnFinish(opts)
 	}
 
+	if !atomic.CompareAndSwapInt32(&s.finished, 0, 1) {
+		// the span is reported only once
+		return
+	}
+
 	for _, lr := range opts.LogRecords {
 		s.logFields(lr.Timestamp, lr.Fields...)
 	}
+	for _, ld := range opts.BulkLogData {
+		s.Log(ld)
+	}
 
-	if !opts.FinishTime.IsZero() {
-		f, ok := s.zipkinSpan.(FinisherWithDuration)
-		if !ok {
-			return
+	finishTime := opts.FinishTime
+	if finishTime.IsZero() {
+		finishTime = time.Now()
+	}
+	s.finish(finishTime)
+}
+
+// finish records the span duration up to finishTime and sends the span to
+// the reporter, honoring a sampling decision forced after span creation.
+func (s *spanImpl) finish(finishTime time.Time) {
+	defer s.tracer.spans.done(s)
+
+	s.decorateFinish()
+	s.applyFinishTags()
+	s.report(s.duration(finishTime))
+}
+
+// decorateFinish calls the finish decorators with the latest operation name.
+func (s *spanImpl) decorateFinish() {
+	decorators := s.tracer.opts.finishDecorators
+	if len(decorators) == 0 {
+		return
+	}
+	s.mtx.RLock()
+	operationName := s.operationName
+	s.mtx.RUnlock()
+	for _, decorate := range decorators {
+		decorate(operationName, s.startOptions, decoratorSpan{s.zipkinSpan, s})
+	}
+}
+
+// applyFinishTags applies the kind, peer, error and dropped data tags to the
+// zipkin span and records the finished span in the tracer Stats.
+func (s *spanImpl) applyFinishTags() {
+	s.applyPeerTags()
+	s.applyError()
+	droppedTags, droppedAnnotations := s.limiter.droppedCounts()
+	s.tracer.stats.DroppedTags.add(int64(droppedTags))
+	s.tracer.stats.DroppedAnnotations.add(int64(droppedAnnotations))
+	for k, v := range s.limiter.dropped() {
+		s.zipkinSpan.Tag(k, v)
+	}
+	s.mtx.RLock()
+	s.tracer.stats.SpansFinished.kind(s.kind).inc()
+	s.mtx.RUnlock()
+}
+
+// duration returns the duration of the span finished at finishTime.
+func (s *spanImpl) duration(finishTime time.Time) time.Duration {
+	startTime := s.startTime
+	if m := spanModel(s.zipkinSpan); m != nil {
+		// the zipkin span timestamp is authoritative
+		startTime = m.Timestamp
+	}
+	d := finishTime.Sub(startTime)
+	if d < 0 {
+		// Zipkin does not accept negative durations
+		return 0
+	}
+	return d
+}
+
+// samplingDecision returns whether the span was sampled on creation and
+// whether it is reported, which differs if the decision was forced after
+// creation.
+func (s *spanImpl) samplingDecision() (collected, reported, forced bool) {
+	// the zipkin SpanContext holds the decision made on creation
+	sc := s.zipkinSpan.Context()
+	collected = sc.Debug || (sc.Sampled != nil && *sc.Sampled)
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.forcedSampling != nil {
+		return collected, *s.forcedSampling, true
+	}
+	return collected, collected, false
+}
+
+// report finishes the zipkin span with duration d and sends it to the
+// reporter. The zipkin span keeps the sampling decision made on creation, a
+// decision forced afterwards is reported with a copy of its model.
+func (s *spanImpl) report(d time.Duration) {
+	collected, reported, forced := s.samplingDecision()
+	if collected && !reported {
+		s.tracer.stats.SpansDropped.inc()
+	}
+
+	if s.tracer.isClosed() {
+		// the reporter might be closed
+		if reported {
+			s.tracer.stats.SpansDropped.inc()
 		}
-		f.FinishedWithDuration(opts.FinishTime.Sub(s.startTime))
 		return
 	}
 
-	s.Finish()
+	// zipkin-go reports self-reporting spans on finish if they were sampled
+	// on creation
+	selfReported := s.selfReporting && collected
+	if selfReported && !reported {
+		return
+	}
+	s.zipkinSpan.FinishedWithDuration(d)
+	if !reported || selfReported {
+		return
+	}
+	if forced {
+		s.reportForced(d)
+		return
+	}
+	s.zipkinSpan.Flush()
+}
+
+// reportForced sends a copy of the zipkin span model carrying the sampling
+// decision forced by a sampling.priority tag through the reporter set by
+// WithReporter. zipkin-go does not record the duration of spans which were
+// not sampled on creation.
+func (s *spanImpl) reportForced(d time.Duration) {
+	m := spanModel(s.zipkinSpan)
+	if m == nil {
+		// sampling is never forced on noop spans
+		return
+	}
+	r := s.tracer.opts.reporter
+	if r == nil {
+		s.tracer.stats.SpansDropped.inc()
+		s.tracer.logger.warn("forced sampling decision requires WithReporter to be reported",
+			"operation", s.tracer.opts.sanitizeOperationName(m.Name))
+		return
+	}
+
+	sm := *m
+	sampled := true
+	sm.Debug = true
+	sm.Sampled = &sampled
+	sm.Duration = d
+	r.Send(sm)
 }
 
 func (s *spanImpl) Tracer() opentracing.Tracer {
@@ -147,13 +504,53 @@ func (s *spanImpl) Tracer() opentracing.Tracer {
 }
 
 func (s *spanImpl) Context() opentracing.SpanContext {
-	return SpanContext(s.zipkinSpan.Context())
+	sc := s.withForcedSampling(s.zipkinSpan.Context())
+	s.mtx.RLock()
+	if s.baggage != nil {
+		sc.Baggage = s.baggage
+	}
+	s.mtx.RUnlock()
+	return SpanContext(sc)
+}
+
+// withForcedSampling returns sc carrying the sampling decision forced by a
+// sampling.priority tag, if any.
+func (s *spanImpl) withForcedSampling(sc model.SpanContext) model.SpanContext {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.forcedSampling != nil {
+		sampled := *s.forcedSampling
+		sc.Debug = sampled
+		sc.Sampled = &sampled
+	}
+	return sc
 }
 
 func (s *spanImpl) SetBaggageItem(key, val string) opentracing.Span {
+	policy := s.tracer.opts.baggagePolicy
+
+	s.mtx.Lock()
+	var (
+		admitted = val
+		err      error
+	)
+	if policy != nil {
+		admitted, err = policy.admitItem(s.baggage, key, val)
+	}
+	if err == nil {
+		s.baggage = s.baggage.withItem(key, admitted)
+	}
+	s.mtx.Unlock()
+
+	if err != nil {
+		policy.reject(key, val, err)
+	}
 	return s
 }
 
 func (s *spanImpl) BaggageItem(key string) string {
-	return ""
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.baggage.item(key)
 }
@@ -0,0 +1,155 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Tags recording the number of items dropped because of SpanLimits
+const (
+	DroppedTagsKey        = "otel.dropped_tags"
+	DroppedAnnotationsKey = "otel.dropped_annotations"
+)
+
+// SpanLimits caps the data recorded on a single span. Zero values disable the
+// corresponding cap. Tags exceeding MaxTags or MaxKeyLength and annotations
+// exceeding MaxAnnotations are dropped and counted in the DroppedTagsKey and
+// DroppedAnnotationsKey tags on finish. Values exceeding MaxValueLength are
+// truncated.
+type SpanLimits struct {
+	// MaxTags is the maximum number of distinct tag keys.
+	MaxTags int
+	// MaxAnnotations is the maximum number of annotations.
+	MaxAnnotations int
+	// MaxKeyLength is the maximum length of a tag key in bytes.
+	MaxKeyLength int
+	// MaxValueLength is the maximum length of a tag or annotation value in
+	// bytes.
+	MaxValueLength int
+}
+
+// spanLimiter enforces SpanLimits on a single span. A nil spanLimiter
+// accepts everything.
+type spanLimiter struct {
+	limits *SpanLimits
+
+	mtx                sync.Mutex
+	tagKeys            map[string]struct{}
+	annotations        int
+	droppedTags        int
+	droppedAnnotations int
+}
+
+func newSpanLimiter(limits *SpanLimits) *spanLimiter {
+	if limits == nil {
+		return nil
+	}
+	return &spanLimiter{
+		limits:  limits,
+		tagKeys: make(map[string]struct{}),
+	}
+}
+
+// tag returns the value to record for the tag or false if the tag must be
+// dropped.
+func (l *spanLimiter) tag(key, value string) (string, bool) {
+	if l == nil {
+		return value, true
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.limits.MaxKeyLength > 0 && len(key) > l.limits.MaxKeyLength {
+		l.droppedTags++
+		return "", false
+	}
+	if _, ok := l.tagKeys[key]; !ok {
+		if l.limits.MaxTags > 0 && len(l.tagKeys) >= l.limits.MaxTags {
+			l.droppedTags++
+			return "", false
+		}
+		l.tagKeys[key] = struct{}{}
+	}
+	return truncate(value, l.limits.MaxValueLength), true
+}
+
+// tags returns the tags to record. Tags are admitted in key order so limits
+// are enforced deterministically.
+func (l *spanLimiter) tags(tags map[string]string) map[string]string {
+	if l == nil || len(tags) == 0 {
+		return tags
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	admitted := make(map[string]string, len(tags))
+	for _, k := range keys {
+		if v, ok := l.tag(k, tags[k]); ok {
+			admitted[k] = v
+		}
+	}
+	return admitted
+}
+
+// annotation returns the value to record for the annotation or false if the
+// annotation must be dropped.
+func (l *spanLimiter) annotation(value string) (string, bool) {
+	if l == nil {
+		return value, true
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.limits.MaxAnnotations > 0 && l.annotations >= l.limits.MaxAnnotations {
+		l.droppedAnnotations++
+		return "", false
+	}
+	l.annotations++
+	return truncate(value, l.limits.MaxValueLength), true
+}
+
+// droppedCounts returns the number of dropped tags and annotations.
+func (l *spanLimiter) droppedCounts() (int, int) {
+	if l == nil {
+		return 0, 0
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.droppedTags, l.droppedAnnotations
+}
+
+// dropped returns the tags recording the number of dropped items.
+func (l *spanLimiter) dropped() map[string]string {
+	if l == nil {
+		return nil
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	tags := make(map[string]string, 2)
+	if l.droppedTags > 0 {
+		tags[DroppedTagsKey] = strconv.Itoa(l.droppedTags)
+	}
+	if l.droppedAnnotations > 0 {
+		tags[DroppedAnnotationsKey] = strconv.Itoa(l.droppedAnnotations)
+	}
+	return tags
+}
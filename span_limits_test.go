@@ -0,0 +1,99 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"strconv"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanLimits(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithSpanLimits(SpanLimits{
+		MaxTags:        3,
+		MaxAnnotations: 2,
+		MaxKeyLength:   8,
+		MaxValueLength: 6,
+	}))
+
+	span := tracer.StartSpan("x", opentracing.Tags{"a": "1", "b": "2"})
+	span.SetTag("a", "overwritten")
+	span.SetTag("much-too-long", "x")
+	for i := 0; i < 100; i++ {
+		span.SetTag("tag"+strconv.Itoa(i), i)
+		span.LogKV("event", i)
+	}
+	span.SetTag("error", true)
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, map[string]string{
+		"a":                   "ove...",
+		"b":                   "2",
+		"tag0":                "0",
+		"error":               "true",
+		DroppedTagsKey:        "100",
+		DroppedAnnotationsKey: "98",
+	}, spans[0].Tags)
+	assert.Equal(t, 2, len(spans[0].Annotations))
+}
+
+func TestSpanLimitsLinks(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithSpanLimits(SpanLimits{MaxTags: 4}))
+
+	refs := []opentracing.StartSpanOption{opentracing.Tag{Key: "a", Value: "1"}}
+	for i := 0; i < 10; i++ {
+		producer := tracer.StartSpan("producer")
+		refs = append(refs, opentracing.FollowsFrom(producer.Context()))
+		producer.Finish()
+	}
+	rec.Flush()
+
+	span := tracer.StartSpan("fan-in", refs...)
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, 4+1, len(spans[0].Tags))
+	assert.Equal(t, "1", spans[0].Tags["a"])
+	// the first reference is the parent, the others are links of 3 tags
+	assert.Equal(t, strconv.Itoa(3*9-3), spans[0].Tags[DroppedTagsKey])
+	assert.Equal(t, int64(3*9-3), TracerStats(tracer).DroppedTags.Value())
+}
+
+func TestSpanLimitsDisabled(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr)
+
+	span := tracer.StartSpan("x")
+	for i := 0; i < 100; i++ {
+		span.SetTag("tag"+strconv.Itoa(i), i)
+	}
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, 100, len(spans[0].Tags))
+}
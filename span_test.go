@@ -15,13 +15,18 @@
 package zipkintracer
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/openzipkin/zipkin-go"
 
 	"github.com/openzipkin/zipkin-go/reporter/recorder"
 
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
+	"github.com/openzipkin/zipkin-go/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -82,3 +87,299 @@ func TestSpan_TrimUnsampledSpans(t *testing.T) {
 	spans = recorder.Flush()
 	assert.Equal(t, 0, len(spans))
 }
+
+func TestSpan_Baggage(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tracer := newTracer(recorder)
+
+	parent := tracer.StartSpan("parent")
+	parent.SetBaggageItem("key1", "value1")
+	assert.Equal(t, "value1", parent.BaggageItem("key1"))
+	assert.Equal(t, "", parent.BaggageItem("missing"))
+
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	child.SetBaggageItem("key2", "value2")
+	assert.Equal(t, "value1", child.BaggageItem("key1"))
+	assert.Equal(t, "value2", child.BaggageItem("key2"))
+
+	// baggage set on the child must not leak into the parent
+	assert.Equal(t, "", parent.BaggageItem("key2"))
+
+	items := map[string]string{}
+	child.Context().ForeachBaggageItem(func(k, v string) bool {
+		items[k] = v
+		return true
+	})
+	assert.Equal(t, map[string]string{"key1": "value1", "key2": "value2"}, items)
+
+	var count int
+	child.Context().ForeachBaggageItem(func(k, v string) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+
+	child.Finish()
+	parent.Finish()
+}
+
+func TestSpan_PeerTagsAfterCreation(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tracer := newTracer(recorder)
+
+	span := tracer.StartSpan("x", ext.SpanKindRPCClient, opentracing.Tag{Key: string(ext.PeerService), Value: "db"})
+	ext.SpanKindProducer.Set(span)
+	span.SetTag(string(ext.PeerHostIPv4), "10.0.0.1")
+	span.SetTag(string(ext.PeerPort), 3306)
+	span.SetTag(string(ext.PeerHostname), "db.local")
+	span.Finish()
+
+	spans := recorder.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, model.Producer, spans[0].Kind)
+	assert.Equal(t, "db", spans[0].RemoteEndpoint.ServiceName)
+	assert.Equal(t, "10.0.0.1", spans[0].RemoteEndpoint.IPv4.String())
+	assert.Equal(t, uint16(3306), spans[0].RemoteEndpoint.Port)
+	assert.Equal(t, map[string]string{"peer.hostname": "db.local"}, spans[0].Tags)
+}
+
+func TestSpan_SamplingPriority(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(recorder, zipkin.WithSampler(zipkin.NeverSample))
+	tracer := Wrap(tr, WithReporter(recorder))
+
+	// forced on creation
+	span := tracer.StartSpan("x", opentracing.Tag{Key: string(ext.SamplingPriority), Value: 1})
+	child := tracer.StartSpan("y", opentracing.ChildOf(span.Context()))
+	child.Finish()
+	span.Finish()
+	spans := recorder.Flush()
+	assert.Equal(t, 2, len(spans))
+	assert.True(t, spans[1].Debug)
+	assert.Equal(t, 0, len(spans[1].Tags))
+
+	// forced after creation of a root span
+	span = tracer.StartSpan("x")
+	ext.SamplingPriority.Set(span, 1)
+	sc := span.Context().(SpanContext)
+	assert.True(t, sc.Debug)
+	child = tracer.StartSpan("y", opentracing.ChildOf(sc))
+	child.Finish()
+	span.Finish()
+	spans = recorder.Flush()
+	assert.Equal(t, 2, len(spans))
+	assert.True(t, spans[1].Debug)
+	assert.True(t, spans[1].Duration > 0)
+
+	// zipkin-go children follow the decision forced after creation
+	span = tracer.StartSpan("x")
+	ext.SamplingPriority.Set(span, 1)
+	zs, _ := ZipkinSpan(span)
+	assert.True(t, zs.Context().Debug)
+	tr.StartSpan("native", zipkin.Parent(zs.Context())).Finish()
+	span.Finish()
+	assert.Equal(t, 2, len(recorder.Flush()))
+
+	// forced decisions are not reported without reporter
+	span = newTracer(recorder, zipkin.WithSampler(zipkin.NeverSample)).StartSpan("x")
+	ext.SamplingPriority.Set(span, 1)
+	span.Finish()
+	assert.Equal(t, 0, len(recorder.Flush()))
+
+	// suppressed
+	tracer = newTracer(recorder, zipkin.WithSampler(zipkin.AlwaysSample))
+	span = tracer.StartSpan("x", opentracing.Tag{Key: string(ext.SamplingPriority), Value: uint16(0)})
+	span.Finish()
+	span = tracer.StartSpan("x")
+	span.SetTag(string(ext.SamplingPriority), "0")
+	span.Finish()
+	assert.Equal(t, 0, len(recorder.Flush()))
+
+	// ignored on child spans
+	parent := tracer.StartSpan("x")
+	child = tracer.StartSpan("y", opentracing.ChildOf(parent.Context()))
+	ext.SamplingPriority.Set(child, 0)
+	child.Finish()
+	child.Finish()
+	assert.Equal(t, 1, len(recorder.Flush()))
+}
+
+func TestSpan_SamplingPriorityConcurrentFinish(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(recorder, zipkin.WithSampler(zipkin.NeverSample))
+	tracer := Wrap(tr, WithReporter(recorder))
+
+	span := tracer.StartSpan("x")
+	ext.SamplingPriority.Set(span, 1)
+	zs, _ := ZipkinSpan(span)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = zs.Context()
+		}
+	}()
+	span.Finish()
+	<-done
+
+	spans := recorder.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.True(t, spans[0].Debug)
+}
+
+func TestSpan_ErrorTag(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tracer := newTracer(recorder)
+
+	span := tracer.StartSpan("x", opentracing.Tag{Key: string(ext.Error), Value: false})
+	span.Finish()
+	span = tracer.StartSpan("x")
+	ext.Error.Set(span, false)
+	span.Finish()
+	span = tracer.StartSpan("x")
+	ext.Error.Set(span, true)
+	span.Finish()
+	span = tracer.StartSpan("x", opentracing.Tag{Key: string(ext.Error), Value: true})
+	span.LogFields(log.String("event", "error"), log.Error(errors.New("boom")))
+	span.Finish()
+	span = tracer.StartSpan("x")
+	span.LogKV("event", "error", "error.kind", "Timeout", "message", "took too long")
+	span.Finish()
+	span = tracer.StartSpan("x")
+	span.LogEventWithPayload("error", errors.New("payload"))
+	span.Finish()
+
+	spans := recorder.Flush()
+	assert.Equal(t, 6, len(spans))
+	assert.Equal(t, 0, len(spans[0].Tags))
+	assert.Equal(t, 0, len(spans[1].Tags))
+	assert.Equal(t, map[string]string{"error": "true"}, spans[2].Tags)
+	assert.Equal(t, map[string]string{"error": "boom"}, spans[3].Tags)
+	assert.Equal(t, 2, len(spans[3].Annotations))
+	assert.Equal(t, map[string]string{"error": "took too long"}, spans[4].Tags)
+	assert.Equal(t, map[string]string{"error": "payload"}, spans[5].Tags)
+}
+
+func TestSpan_ErrorLogTags(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(recorder)
+	tracer := Wrap(tr, WithErrorLogOption(ErrorLogTags))
+
+	span := tracer.StartSpan("x")
+	span.LogFields(
+		log.String("event", "error"),
+		log.String("error.kind", "Timeout"),
+		log.Error(errors.New("boom")),
+		log.String("stack", "main.go:1"),
+	)
+	span.Finish()
+
+	spans := recorder.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, 0, len(spans[0].Annotations))
+	assert.Equal(t, map[string]string{
+		"error":        "boom",
+		"error.kind":   "Timeout",
+		"error.object": "boom",
+		"stack":        "main.go:1",
+	}, spans[0].Tags)
+}
+
+func TestSpan_FinishWithOptions(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tracer := newTracer(recorder)
+
+	start := time.Now().Add(-time.Minute)
+	span := tracer.StartSpan("x", opentracing.StartTime(start))
+	span.FinishWithOptions(opentracing.FinishOptions{
+		FinishTime: start.Add(time.Second),
+		LogRecords: []opentracing.LogRecord{
+			{Timestamp: start, Fields: []log.Field{log.String("key", "value")}},
+		},
+		BulkLogData: []opentracing.LogData{
+			{Timestamp: start, Event: "event"},
+		},
+	})
+	// finishing again must not report the span twice
+	span.Finish()
+	span.FinishWithOptions(opentracing.FinishOptions{FinishTime: time.Now()})
+
+	spans := recorder.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, start.UnixNano(), spans[0].Timestamp.UnixNano())
+	assert.Equal(t, time.Second, spans[0].Duration)
+	assert.Equal(t, 2, len(spans[0].Annotations))
+	assert.Equal(t, "key:value", spans[0].Annotations[0].Value)
+	assert.Equal(t, "event", spans[0].Annotations[1].Value)
+
+	// back-dated finish before the start time
+	span = tracer.StartSpan("x")
+	span.FinishWithOptions(opentracing.FinishOptions{FinishTime: time.Now().Add(-time.Hour)})
+	spans = recorder.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, time.Duration(0), spans[0].Duration)
+
+	// finish time without explicit start time
+	span = tracer.StartSpan("x")
+	span.FinishWithOptions(opentracing.FinishOptions{FinishTime: time.Now().Add(time.Hour)})
+	spans = recorder.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.True(t, spans[0].Duration >= time.Hour)
+}
+
+func TestSpan_FinishWithOptionsNoop(t *testing.T) {
+	recorder := recorder.NewReporter()
+	tracer := newTracer(
+		recorder,
+		zipkin.WithSampler(zipkin.NeverSample),
+		zipkin.WithNoopSpan(true),
+	)
+
+	span := tracer.StartSpan("x")
+	span.FinishWithOptions(opentracing.FinishOptions{FinishTime: time.Now()})
+	assert.Equal(t, 0, len(recorder.Flush()))
+
+	// forcing sampling of a noop span has no effect and is not propagated
+	span = tracer.StartSpan("x")
+	ext.SamplingPriority.Set(span, 1)
+	sc := span.Context().(SpanContext)
+	assert.False(t, sc.Debug)
+	assert.False(t, sc.Sampled != nil && *sc.Sampled)
+	span.FinishWithOptions(opentracing.FinishOptions{FinishTime: time.Now()})
+	assert.Equal(t, 0, len(recorder.Flush()))
+}
+
+type nilPointerError struct {
+	message string
+}
+
+func (e *nilPointerError) Error() string {
+	return e.message
+}
+
+func TestSpan_NilPointerError(t *testing.T) {
+	recorder := recorder.NewReporter()
+	var err *nilPointerError
+
+	tracer := newTracer(recorder)
+	span := tracer.StartSpan("x")
+	span.SetTag(string(ext.Error), err)
+	span.Finish()
+	span = tracer.StartSpan("x")
+	span.LogFields(log.Error(err))
+	span.Finish()
+
+	tr, _ := zipkin.NewTracer(recorder)
+	tracer = Wrap(tr, WithLogEncoder(JSONLogEncoder))
+	span = tracer.StartSpan("x")
+	span.LogFields(log.Error(err))
+	span.Finish()
+
+	spans := recorder.Flush()
+	assert.Equal(t, 3, len(spans))
+	for _, span := range spans {
+		assert.Equal(t, "true", span.Tags["error"])
+	}
+	assert.Equal(t, `{"error.object":null}`, spans[2].Annotations[0].Value)
+}
@@ -0,0 +1,184 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/propagation/b3"
+)
+
+// Counter is a monotonically increasing counter safe for concurrent use.
+type Counter struct {
+	n int64
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *Counter) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, c.Value(), 10), nil
+}
+
+func (c *Counter) add(n int64) {
+	atomic.AddInt64(&c.n, n)
+}
+
+func (c *Counter) inc() {
+	atomic.AddInt64(&c.n, 1)
+}
+
+// KindCounters counts spans per span kind.
+type KindCounters struct {
+	Undetermined Counter `json:"undetermined"`
+	Client       Counter `json:"client"`
+	Server       Counter `json:"server"`
+	Producer     Counter `json:"producer"`
+	Consumer     Counter `json:"consumer"`
+}
+
+func (c *KindCounters) kind(kind model.Kind) *Counter {
+	switch kind {
+	case model.Client:
+		return &c.Client
+	case model.Server:
+		return &c.Server
+	case model.Producer:
+		return &c.Producer
+	case model.Consumer:
+		return &c.Consumer
+	default:
+		return &c.Undetermined
+	}
+}
+
+// PropagationCounters counts the results of Inject or Extract calls. Invalid
+// B3 headers count as ErrSpanContextCorrupted and extracted contexts without
+// trace as ErrSpanContextNotFound.
+type PropagationCounters struct {
+	Success                 Counter `json:"success"`
+	ErrSpanContextNotFound  Counter `json:"err_span_context_not_found"`
+	ErrSpanContextCorrupted Counter `json:"err_span_context_corrupted"`
+	ErrInvalidCarrier       Counter `json:"err_invalid_carrier"`
+	ErrInvalidSpanContext   Counter `json:"err_invalid_span_context"`
+	ErrUnsupportedFormat    Counter `json:"err_unsupported_format"`
+	OtherErrors             Counter `json:"other_errors"`
+}
+
+func (c *PropagationCounters) record(err error) {
+	switch {
+	case err == nil:
+		c.Success.inc()
+	case errors.Is(err, opentracing.ErrSpanContextNotFound):
+		c.ErrSpanContextNotFound.inc()
+	case errors.Is(err, opentracing.ErrSpanContextCorrupted), isB3Corrupted(err):
+		c.ErrSpanContextCorrupted.inc()
+	case errors.Is(err, opentracing.ErrInvalidCarrier):
+		c.ErrInvalidCarrier.inc()
+	case errors.Is(err, opentracing.ErrInvalidSpanContext), errors.Is(err, b3.ErrEmptyContext):
+		c.ErrInvalidSpanContext.inc()
+	case errors.Is(err, opentracing.ErrUnsupportedFormat):
+		c.ErrUnsupportedFormat.inc()
+	default:
+		c.OtherErrors.inc()
+	}
+}
+
+// isB3Corrupted reports whether err is a B3 error caused by invalid headers.
+func isB3Corrupted(err error) bool {
+	switch err {
+	case b3.ErrInvalidSampledByte, b3.ErrInvalidSampledHeader, b3.ErrInvalidFlagsHeader,
+		b3.ErrInvalidTraceIDHeader, b3.ErrInvalidSpanIDHeader, b3.ErrInvalidParentSpanIDHeader,
+		b3.ErrInvalidScope, b3.ErrInvalidScopeParent, b3.ErrInvalidScopeParentSingle,
+		b3.ErrInvalidTraceIDValue, b3.ErrInvalidSpanIDValue, b3.ErrInvalidParentSpanIDValue:
+		return true
+	}
+	return false
+}
+
+// FormatCounters counts the results of Inject or Extract calls per format.
+// Other holds the counts of the accessor and unsupported formats.
+type FormatCounters struct {
+	Binary      PropagationCounters `json:"binary"`
+	TextMap     PropagationCounters `json:"text_map"`
+	HTTPHeaders PropagationCounters `json:"http_headers"`
+	Other       PropagationCounters `json:"other"`
+}
+
+func (c *FormatCounters) format(format interface{}) *PropagationCounters {
+	switch format {
+	case opentracing.Binary:
+		return &c.Binary
+	case opentracing.TextMap:
+		return &c.TextMap
+	case opentracing.HTTPHeaders:
+		return &c.HTTPHeaders
+	default:
+		return &c.Other
+	}
+}
+
+// Stats holds the counters of a tracer returned by Wrap. It implements
+// expvar.Var, see WithExpvar.
+type Stats struct {
+	// SpansStarted counts started spans per the kind they are started with.
+	SpansStarted KindCounters `json:"spans_started"`
+	// SpansFinished counts finished spans per their final kind.
+	SpansFinished KindCounters `json:"spans_finished"`
+	// SpansSampled and SpansUnsampled count started spans per their
+	// sampling decision on start.
+	SpansSampled   Counter `json:"spans_sampled"`
+	SpansUnsampled Counter `json:"spans_unsampled"`
+	// SpansDropped counts finished spans which are not reported although
+	// sampled, because they finished after Flush or Close or their sampling
+	// was suppressed by a sampling.priority tag set after creation.
+	SpansDropped Counter `json:"spans_dropped"`
+
+	Inject  FormatCounters `json:"inject"`
+	Extract FormatCounters `json:"extract"`
+
+	// ObserverCallbacks counts the calls of the observer and span observers.
+	ObserverCallbacks Counter `json:"observer_callbacks"`
+	// DroppedTags and DroppedAnnotations count the items dropped because of
+	// SpanLimits.
+	DroppedTags        Counter `json:"dropped_tags"`
+	DroppedAnnotations Counter `json:"dropped_annotations"`
+}
+
+// String implements expvar.Var, returning the counters as JSON object.
+func (s *Stats) String() string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// TracerStats returns the counters of a tracer returned by Wrap or nil for
+// other tracers.
+func TracerStats(tracer opentracing.Tracer) *Stats {
+	if t, ok := tracer.(*tracerImpl); ok {
+		return &t.stats
+	}
+	return nil
+}
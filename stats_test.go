@@ -0,0 +1,112 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	otobserver "github.com/opentracing-contrib/go-observer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopObserver struct{}
+
+func (nopObserver) OnStartSpan(opentracing.Span, string, opentracing.StartSpanOptions) (otobserver.SpanObserver, bool) {
+	return nopSpanObserver{}, true
+}
+
+type nopSpanObserver struct{}
+
+func (nopSpanObserver) OnSetOperationName(string)          {}
+func (nopSpanObserver) OnSetTag(string, interface{})       {}
+func (nopSpanObserver) OnFinish(opentracing.FinishOptions) {}
+
+func TestStats(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec, zipkin.WithSampler(zipkin.NeverSample))
+	tracer := Wrap(tr,
+		WithObserver(nopObserver{}),
+		WithSpanLimits(SpanLimits{MaxTags: 1}),
+		WithExpvar("zipkintracer_test_stats"),
+	)
+
+	server := tracer.StartSpan("server", ext.SpanKindRPCServer)
+	server.SetTag("a", 1)
+	server.SetTag("b", 2)
+	server.Finish()
+	client := tracer.StartSpan("client", opentracing.Tag{Key: string(ext.SamplingPriority), Value: 1})
+	client.SetTag(string(ext.SpanKind), ext.SpanKindRPCClientEnum)
+	client.Finish()
+
+	carrier := opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(client.Context(), opentracing.TextMap, carrier))
+	_, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier{})
+	assert.NoError(t, err)
+	_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{"x-b3-traceid": "invalid"})
+	assert.Error(t, err)
+	_, err = tracer.Extract("unknown", carrier)
+	assert.Equal(t, opentracing.ErrUnsupportedFormat, err)
+
+	stats := TracerStats(tracer)
+	assert.Equal(t, int64(1), stats.SpansStarted.Server.Value())
+	assert.Equal(t, int64(1), stats.SpansStarted.Undetermined.Value())
+	assert.Equal(t, int64(1), stats.SpansFinished.Server.Value())
+	assert.Equal(t, int64(1), stats.SpansFinished.Client.Value())
+	assert.Equal(t, int64(1), stats.SpansSampled.Value())
+	assert.Equal(t, int64(1), stats.SpansUnsampled.Value())
+	assert.Equal(t, int64(1), stats.Inject.TextMap.Success.Value())
+	assert.Equal(t, int64(1), stats.Extract.HTTPHeaders.ErrSpanContextNotFound.Value())
+	assert.Equal(t, int64(1), stats.Extract.TextMap.ErrSpanContextCorrupted.Value())
+	assert.Equal(t, int64(1), stats.Extract.Other.ErrUnsupportedFormat.Value())
+	// 2 spans started, 3 tags set and 2 spans finished
+	assert.Equal(t, int64(7), stats.ObserverCallbacks.Value())
+	assert.Equal(t, int64(1), stats.DroppedTags.Value())
+
+	var published map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get("zipkintracer_test_stats").String()), &published))
+	assert.Equal(t, float64(1), published["dropped_tags"])
+	assert.Equal(t, float64(1), published["spans_started"].(map[string]interface{})["server"])
+
+	assert.Nil(t, TracerStats(opentracing.NoopTracer{}))
+}
+
+func TestStatsSpansDropped(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr)
+
+	// suppressed after creation
+	span := tracer.StartSpan("suppressed")
+	ext.SamplingPriority.Set(span, 0)
+	span.Finish()
+	// not sampled in the first place
+	tracer.StartSpan("unsampled", opentracing.Tag{Key: string(ext.SamplingPriority), Value: 0}).Finish()
+	// finished after close
+	span = tracer.StartSpan("late")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, tracer.(*tracerImpl).Flush(ctx))
+	span.Finish()
+
+	assert.Equal(t, 0, len(rec.Flush()))
+	assert.Equal(t, int64(2), TracerStats(tracer).SpansDropped.Value())
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// TagEncoder encodes the value of an OpenTracing tag into the string value of
+// a Zipkin tag.
+type TagEncoder func(key string, value interface{}) string
+
+// DefaultTagEncoder encodes tag values based on their type:
+//   - numbers and booleans in their canonical form
+//   - []byte as UTF-8 string if valid, base64 otherwise
+//   - time.Time in RFC 3339 (ISO 8601) format
+//   - time.Duration in milliseconds
+//   - error and fmt.Stringer values through their Error and String methods
+//   - maps, structs, slices and arrays as JSON
+//   - nil and nil pointers as empty string
+//
+// All other values are formatted using fmt.Sprint.
+func DefaultTagEncoder(_ string, value interface{}) string {
+	if isNilPointer(value) {
+		// the Error or String methods of nil pointers might panic
+		return ""
+	}
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return strconv.FormatFloat(float64(v)/float64(time.Millisecond), 'f', -1, 64)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	return encodeKind(value)
+}
+
+// encodeKind encodes values of types not known to DefaultTagEncoder, such as
+// named types, based on their kind.
+func encodeKind(value interface{}) string {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return ""
+		}
+		if k := rv.Elem().Kind(); k == reflect.Struct || k == reflect.Map {
+			return encodeJSON(value)
+		}
+		return DefaultTagEncoder("", rv.Elem().Interface())
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		return encodeJSON(value)
+	}
+	return fmt.Sprint(value)
+}
+
+func encodeJSON(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// encodeTag encodes a tag value using the configured TagEncoder.
+func (opts *TracerOptions) encodeTag(key string, value interface{}) string {
+	if opts.tagEncoder != nil {
+		return opts.tagEncoder(key, value)
+	}
+	return DefaultTagEncoder(key, value)
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+type tagStruct struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestDefaultTagEncoder(t *testing.T) {
+	str := "pointer"
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"value", "value"},
+		{true, "true"},
+		{-42, "-42"},
+		{uint64(18446744073709551615), "18446744073709551615"},
+		{float32(0.1), "0.1"},
+		{1.0 / 3, "0.3333333333333333"},
+		{1e21, "1e+21"},
+		{[]byte("text"), "text"},
+		{[]byte{0xff, 0x00}, "/wA="},
+		{time.Date(2022, 10, 11, 12, 13, 14, 0, time.UTC), "2022-10-11T12:13:14Z"},
+		{1500 * time.Microsecond, "1.5"},
+		{fmt.Errorf("wrapped: %w", errors.New("cause")), "wrapped: cause"},
+		{net.IPv4(10, 0, 0, 1), "10.0.0.1"},
+		{ext.SpanKindRPCClientEnum, "client"},
+		{tagStruct{Name: "a", Count: 1}, `{"name":"a","count":1}`},
+		{&tagStruct{Name: "b"}, `{"name":"b","count":0}`},
+		{map[string]int{"a": 1}, `{"a":1}`},
+		{[]int{1, 2}, "[1,2]"},
+		{&str, "pointer"},
+		{(*tagStruct)(nil), ""},
+		// the Error method panics on nil pointers
+		{(*nilPointerError)(nil), ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, DefaultTagEncoder("key", c.value), "%#v", c.value)
+	}
+
+	// unsupported types fall back to fmt.Sprint
+	ch := make(chan int)
+	assert.Equal(t, fmt.Sprint(ch), DefaultTagEncoder("key", ch))
+}
+
+func TestWithTagEncoder(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithTagEncoder(func(key string, value interface{}) string {
+		return strings.ToUpper(DefaultTagEncoder(key, value))
+	}))
+
+	span := tracer.StartSpan("x", opentracing.Tag{Key: "start", Value: "a"})
+	span.SetTag("late", []byte("b"))
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, map[string]string{"start": "A", "late": "B"}, spans[0].Tags)
+}
@@ -0,0 +1,283 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// zipkinOptions returns the span options setting the provided span kind,
+// remote endpoint and tags.
+func zipkinOptions(
+	kind model.Kind, remoteEndpoint *model.Endpoint, tags map[string]string,
+) []zipkin.SpanOption {
+	zopts := make([]zipkin.SpanOption, 0)
+
+	if kind != model.Undetermined {
+		zopts = append(zopts, zipkin.Kind(kind))
+	}
+
+	if len(tags) > 0 {
+		zopts = append(zopts, zipkin.Tags(tags))
+	}
+
+	if !remoteEndpoint.Empty() {
+		zopts = append(zopts, zipkin.RemoteEndpoint(remoteEndpoint))
+	}
+
+	return zopts
+}
+
+// parseTags translates OpenTracing tags into the Zipkin span kind, remote
+// endpoint and tags. Tags which can not be translated are reported to the
+// logger by key and value type, as values are not sanitized, and kept as is.
+func parseTags(
+	t map[string]interface{}, encode TagEncoder, logger *rateLimitedLogger,
+) (model.Kind, *model.Endpoint, map[string]string) {
+	var (
+		kind           = model.Undetermined
+		remoteEndpoint = &model.Endpoint{}
+		tags           = map[string]string{}
+	)
+
+	for key, val := range t {
+		if key == string(ext.SpanKind) {
+			var ok bool
+			if kind, ok = parseKind(val); !ok {
+				logger.warn("unsupported span.kind tag value", "type", fmt.Sprintf("%T", val))
+				tags[key] = encode(key, val)
+			}
+			continue
+		}
+
+		if setPeerTag(remoteEndpoint, key, val) {
+			continue
+		}
+		if isStrictPeerTag(key) {
+			logger.warn("unsupported peer tag value", "key", key, "type", fmt.Sprintf("%T", val))
+		}
+
+		if key == string(ext.Error) {
+			if message, ok := parseErrorTag(val); ok {
+				if message == "" {
+					message = defaultErrorMessage
+				}
+				tags[key] = message
+			}
+			continue
+		}
+
+		if key == string(ext.SamplingPriority) {
+			if _, ok := parseInt(val); ok {
+				// translated into the sampling decision of the span
+				continue
+			}
+			logger.warn("unsupported sampling.priority tag value", "type", fmt.Sprintf("%T", val))
+		}
+
+		tags[key] = encode(key, val)
+	}
+
+	return kind, remoteEndpoint, tags
+}
+
+// parseKind translates the value of a span.kind tag into a Zipkin span kind.
+func parseKind(val interface{}) (model.Kind, bool) {
+	var kind string
+	switch kindVal := val.(type) {
+	case ext.SpanKindEnum:
+		kind = string(kindVal)
+	case string:
+		kind = kindVal
+	default:
+		kind = fmt.Sprintf("%v", kindVal)
+	}
+	mKind := model.Kind(strings.ToUpper(kind))
+	if mKind == model.Client ||
+		mKind == model.Server ||
+		mKind == model.Producer ||
+		mKind == model.Consumer {
+		return mKind, true
+	}
+	return model.Undetermined, false
+}
+
+// setPeerTag translates a peer.* tag into the remote endpoint. It returns
+// false if key is not a peer tag or its value can not be represented by the
+// endpoint.
+func setPeerTag(e *model.Endpoint, key string, val interface{}) bool {
+	switch key {
+	case string(ext.PeerService):
+		e.ServiceName = fmt.Sprint(val)
+	case string(ext.PeerHostIPv4):
+		if ipv4, ok := val.(uint32); ok {
+			e.IPv4 = make(net.IP, net.IPv4len)
+			binary.BigEndian.PutUint32(e.IPv4, ipv4)
+			break
+		}
+		ip := net.ParseIP(fmt.Sprint(val))
+		if ip == nil {
+			return false
+		}
+		e.IPv4 = ip
+	case string(ext.PeerHostIPv6):
+		ip := net.ParseIP(fmt.Sprint(val))
+		if ip == nil {
+			return false
+		}
+		e.IPv6 = ip
+	case string(ext.PeerPort):
+		port, ok := parsePort(val)
+		if !ok {
+			return false
+		}
+		e.Port = port
+	case string(ext.PeerHostname):
+		return setPeerIP(e, fmt.Sprint(val))
+	case string(ext.PeerAddress):
+		return setPeerAddress(e, fmt.Sprint(val))
+	default:
+		return false
+	}
+	return true
+}
+
+// isStrictPeerTag reports whether key is a peer tag which is expected to be
+// translated into the remote endpoint, as opposed to peer.hostname and
+// peer.address which can hold hostnames.
+func isStrictPeerTag(key string) bool {
+	return key == string(ext.PeerHostIPv4) ||
+		key == string(ext.PeerHostIPv6) ||
+		key == string(ext.PeerPort)
+}
+
+// parsePort accepts any integer type or a numeric string as port.
+func parsePort(val interface{}) (uint16, bool) {
+	port, ok := parseInt(val)
+	if !ok || port < 0 || port > 65535 {
+		return 0, false
+	}
+	return uint16(port), true
+}
+
+// parseInt accepts any integer type or a numeric string.
+func parseInt(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return parseInt(uint64(v))
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// setPeerIP sets the endpoint IPv4 or IPv6 address if host is an IP address.
+func setPeerIP(e *model.Endpoint, host string) bool {
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil {
+		return false
+	}
+	if ip.To4() != nil {
+		e.IPv4 = ip.To4()
+	} else {
+		e.IPv6 = ip
+	}
+	return true
+}
+
+// setPeerAddress parses addresses like "ip:port", "ip" or
+// "mysql://user@127.0.0.1:3306/db" into the endpoint. Addresses holding a
+// hostname instead of an IP address are left untouched.
+func setPeerAddress(e *model.Endpoint, address string) bool {
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return false
+		}
+		address = u.Host
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return setPeerIP(e, address)
+	}
+
+	p, ok := parsePort(port)
+	if !ok {
+		return false
+	}
+	var ep model.Endpoint
+	if !setPeerIP(&ep, host) {
+		return false
+	}
+	if ep.IPv4 != nil {
+		e.IPv4 = ep.IPv4
+	}
+	if ep.IPv6 != nil {
+		e.IPv6 = ep.IPv6
+	}
+	e.Port = p
+	return true
+}
+
+// samplingPriority returns the value of the sampling.priority tag if found.
+func samplingPriority(t map[string]interface{}) (int64, bool) {
+	val, ok := t[string(ext.SamplingPriority)]
+	if !ok {
+		return 0, false
+	}
+	return parseInt(val)
+}
+
+// setSamplingPriority forces the sampling decision of sc. A priority above
+// zero results in a sampled debug span, zero results in an unsampled span.
+func setSamplingPriority(sc *model.SpanContext, priority int64) {
+	sampled := priority > 0
+	sc.Debug = sampled
+	sc.Sampled = &sampled
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"net"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePort(t *testing.T) {
+	cases := []struct {
+		value interface{}
+		port  uint16
+		ok    bool
+	}{
+		{uint16(80), 80, true},
+		{8080, 8080, true},
+		{int32(443), 443, true},
+		{int64(65535), 65535, true},
+		{uint64(9411), 9411, true},
+		{"3306", 3306, true},
+		{" 53 ", 53, true},
+		{-1, 0, false},
+		{70000, 0, false},
+		{uint64(70000), 0, false},
+		{"http", 0, false},
+		{1.5, 0, false},
+	}
+	for _, c := range cases {
+		port, ok := parsePort(c.value)
+		assert.Equal(t, c.ok, ok, "%#v", c.value)
+		assert.Equal(t, c.port, port, "%#v", c.value)
+	}
+}
+
+func TestSetPeerTag(t *testing.T) {
+	cases := []struct {
+		key      string
+		value    interface{}
+		endpoint model.Endpoint
+		ok       bool
+	}{
+		{string(ext.PeerService), "db", model.Endpoint{ServiceName: "db"}, true},
+		{string(ext.PeerHostIPv4), "10.0.0.1", model.Endpoint{IPv4: net.ParseIP("10.0.0.1")}, true},
+		{string(ext.PeerHostIPv4), uint32(0x0a000001), model.Endpoint{IPv4: net.IPv4(10, 0, 0, 1).To4()}, true},
+		{string(ext.PeerHostIPv6), "::1", model.Endpoint{IPv6: net.ParseIP("::1")}, true},
+		{string(ext.PeerPort), "8080", model.Endpoint{Port: 8080}, true},
+		{string(ext.PeerPort), "http", model.Endpoint{}, false},
+		{string(ext.PeerHostname), "10.0.0.1", model.Endpoint{IPv4: net.ParseIP("10.0.0.1").To4()}, true},
+		{string(ext.PeerHostname), "db.local", model.Endpoint{}, false},
+		{string(ext.PeerAddress), "10.0.0.1:3306", model.Endpoint{IPv4: net.ParseIP("10.0.0.1").To4(), Port: 3306}, true},
+		{string(ext.PeerAddress), "[::1]:53", model.Endpoint{IPv6: net.ParseIP("::1"), Port: 53}, true},
+		{
+			string(ext.PeerAddress), "mysql://user@127.0.0.1:3306/db",
+			model.Endpoint{IPv4: net.ParseIP("127.0.0.1").To4(), Port: 3306}, true,
+		},
+		{string(ext.PeerAddress), "db.local:3306", model.Endpoint{}, false},
+		{"key", "value", model.Endpoint{}, false},
+	}
+	for _, c := range cases {
+		var e model.Endpoint
+		ok := setPeerTag(&e, c.key, c.value)
+		assert.Equal(t, c.ok, ok, "%s=%v", c.key, c.value)
+		assert.Equal(t, c.endpoint, e, "%s=%v", c.key, c.value)
+	}
+}
@@ -15,9 +15,10 @@
 package zipkintracer
 
 import (
+	"expvar"
 	"fmt"
-	"net"
-	"strings"
+	"strconv"
+	"sync"
 	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
@@ -27,25 +28,55 @@ import (
 )
 
 type tracerImpl struct {
+	// stats and spans hold 64-bit atomic counters and must stay the first
+	// fields to be 64-bit aligned on 32-bit platforms
+	stats Stats
+	spans spanTracker
+
 	zipkinTracer       *zipkin.Tracer
 	textPropagator     *textMapPropagator
 	accessorPropagator *accessorPropagator
 	opts               *TracerOptions
+	operationNames     *operationNameGuard
+
+	closed    int32
+	closeOnce sync.Once
+	closeErr  error
+
+	stopLeakDetection chan struct{}
+
+	logger *rateLimitedLogger
 }
 
 // Wrap receives a zipkin tracer and returns an opentracing
-// tracer
+// tracer. The returned tracer implements io.Closer and
+// Flush(context.Context) error, see WithReporter.
 func Wrap(tr *zipkin.Tracer, opts ...TracerOption) opentracing.Tracer {
 	t := &tracerImpl{
 		zipkinTracer: tr,
 		opts:         &TracerOptions{},
 	}
-	t.textPropagator = &textMapPropagator{t}
+	t.textPropagator = &textMapPropagator{tracer: t}
 	t.accessorPropagator = &accessorPropagator{t}
 
 	for _, o := range opts {
 		o(t.opts)
 	}
+	t.textPropagator.propagators = t.opts.textMapPropagators()
+	t.logger = newRateLimitedLogger(t.opts.logger, DefaultLogRate)
+	if t.opts.baggagePolicy != nil {
+		t.opts.baggagePolicy.logger = t.logger
+	}
+	t.operationNames = newOperationNameGuard(t.opts.maxOperationNames, t.opts.overflowOperationName)
+	if t.opts.expvarName != "" {
+		expvar.Publish(t.opts.expvarName, &t.stats)
+	}
+	// spans are only held to report them as leaked
+	t.spans.hold = t.opts.leakDetection != nil
+	if t.opts.leakDetection != nil && t.opts.leakDetection.Threshold > 0 {
+		t.stopLeakDetection = make(chan struct{})
+		go t.detectLeaks(t.stopLeakDetection)
+	}
 
 	return t
 }
@@ -55,17 +86,30 @@ func (t *tracerImpl) StartSpan(operationName string, opts ...opentracing.StartSp
 	for _, opt := range opts {
 		opt.Apply(&startSpanOptions)
 	}
+	startSpanOptions.Tags = t.withDefaultTags(startSpanOptions.Tags)
 
 	zopts := make([]zipkin.SpanOption, 0)
 
 	// Parent
-	if len(startSpanOptions.References) > 0 {
-		parent, ok := (startSpanOptions.References[0].ReferencedContext).(SpanContext)
-		if ok {
-			zopts = append(zopts, zipkin.Parent(model.SpanContext(parent)))
+	parent, links := t.parseReferences(startSpanOptions.References)
+
+	// Sampling priority
+	priority, hasPriority := samplingPriority(startSpanOptions.Tags)
+	if hasPriority {
+		if parent == nil {
+			parent = &SpanContext{}
 		}
+		setSamplingPriority((*model.SpanContext)(parent), priority)
+	}
+	name, overflowed := t.operationName(operationName)
+	parent = t.sample(parent, name, startSpanOptions.Tags)
+	if parent != nil {
+		zopts = append(zopts, zipkin.Parent(model.SpanContext(*parent)))
 	}
 
+	// Spans are reported by spanImpl so late sampling decisions are honored
+	zopts = append(zopts, zipkin.FlushOnFinish(false))
+
 	startTime := time.Now()
 	// Time
 	if !startSpanOptions.StartTime.IsZero() {
@@ -73,16 +117,55 @@ func (t *tracerImpl) StartSpan(operationName string, opts ...opentracing.StartSp
 		startTime = startSpanOptions.StartTime
 	}
 
-	zopts = append(zopts, parseTagsAsZipkinOptions(startSpanOptions.Tags)...)
+	kind, remoteEndpoint, tags := parseTags(startSpanOptions.Tags, t.opts.encodeTag, t.logger)
+	// the error tag is set on finish as error logs can provide its message
+	errorMessage, errored := tags[string(ext.Error)]
+	delete(tags, string(ext.Error))
+	if overflowed {
+		t.logger.warn("operation name exceeds the operation name limit",
+			"operation", t.opts.sanitizeOperationName(operationName))
+		tags[OriginalOperationNameKey] = operationName
+	}
+	limiter := newSpanLimiter(t.opts.spanLimits)
+	tags = limiter.tags(t.opts.sanitizeTags(tags))
+
+	// Links are admitted within the span limits after the start tags
+	if len(links) > 0 {
+		for k, v := range limiter.tags(linkTags(links)) {
+			tags[k] = v
+		}
+	}
+	zopts = append(zopts, zipkinOptions(kind, remoteEndpoint, tags)...)
 
-	newSpan := t.zipkinTracer.StartSpan(operationName, zopts...)
+	newSpan := t.zipkinTracer.StartSpan(name, zopts...)
 
 	sp := &spanImpl{
 		zipkinSpan: newSpan,
 		tracer:     t,
 		startTime:  startTime,
+		// baggage is inherited from the parent through the zipkin SpanContext
+		baggage:        newBaggage(newSpan.Context().Baggage),
+		kind:           kind,
+		remoteEndpoint: *remoteEndpoint,
+		root:           parent == nil || parent.TraceID.Empty(),
+		errored:        errored,
+		errorMessage:   errorMessage,
+		limiter:        limiter,
+		operationName:  operationName,
+		startOptions:   startSpanOptions,
+	}
+	for _, ref := range startSpanOptions.References {
+		if sc, ok := ref.ReferencedContext.(SpanContext); ok {
+			// the W3C tracestate is only inherited from the parent
+			sp.baggage = sp.baggage.merge(newBaggage(sc.Baggage).withTraceState(""))
+		}
+	}
+	for _, decorate := range t.opts.startDecorators {
+		decorate(operationName, startSpanOptions, decoratorSpan{newSpan, sp})
 	}
+	t.track(sp)
 	if t.opts.observer != nil {
+		t.stats.ObserverCallbacks.inc()
 		observer, _ := t.opts.observer.OnStartSpan(sp, operationName, startSpanOptions)
 		sp.observer = observer
 	}
@@ -90,74 +173,92 @@ func (t *tracerImpl) StartSpan(operationName string, opts ...opentracing.StartSp
 	return sp
 }
 
-func parseTagsAsZipkinOptions(t map[string]interface{}) []zipkin.SpanOption {
-	zopts := make([]zipkin.SpanOption, 0)
-
-	tags := map[string]string{}
-	remoteEndpoint := &model.Endpoint{}
-
-	var kind string
-	if val, ok := t[string(ext.SpanKind)]; ok {
-		switch kindVal := val.(type) {
-		case ext.SpanKindEnum:
-			kind = string(kindVal)
-		case string:
-			kind = kindVal
-		default:
-			kind = fmt.Sprintf("%v", kindVal)
-		}
-		mKind := model.Kind(strings.ToUpper(kind))
-		if mKind == model.Client ||
-			mKind == model.Server ||
-			mKind == model.Producer ||
-			mKind == model.Consumer {
-			zopts = append(zopts, zipkin.Kind(mKind))
-		} else {
-			tags["span.kind"] = kind
-		}
+// withDefaultTags returns the tags merged with the default tags. The provided
+// tags take precedence.
+func (t *tracerImpl) withDefaultTags(tags opentracing.Tags) opentracing.Tags {
+	if len(t.opts.defaultTags) == 0 {
+		return tags
 	}
-
-	if val, ok := t[string(ext.PeerService)]; ok {
-		serviceName, _ := val.(string)
-		remoteEndpoint.ServiceName = serviceName
+	merged := make(opentracing.Tags, len(t.opts.defaultTags)+len(tags))
+	for k, v := range t.opts.defaultTags {
+		merged[k] = v
 	}
-
-	if val, ok := t[string(ext.PeerHostIPv4)]; ok {
-		ipv4, _ := val.(string)
-		remoteEndpoint.IPv4 = net.ParseIP(ipv4)
+	for k, v := range tags {
+		merged[k] = v
 	}
+	return merged
+}
 
-	if val, ok := t[string(ext.PeerHostIPv6)]; ok {
-		ipv6, _ := val.(string)
-		remoteEndpoint.IPv6 = net.ParseIP(ipv6)
+// parseReferences returns the SpanContext to use as parent of a new span and
+// the referenced SpanContexts to record as links. The first ChildOf reference
+// is preferred as parent over FollowsFrom references.
+func (t *tracerImpl) parseReferences(
+	refs []opentracing.SpanReference,
+) (*SpanContext, []opentracing.SpanReference) {
+	primary := -1
+	for i, ref := range refs {
+		if _, ok := ref.ReferencedContext.(SpanContext); !ok {
+			continue
+		}
+		if ref.Type == opentracing.ChildOfRef {
+			primary = i
+			break
+		}
+		if primary < 0 {
+			primary = i
+		}
 	}
-
-	if val, ok := t[string(ext.PeerPort)]; ok {
-		port, _ := val.(uint16)
-		remoteEndpoint.Port = port
+	if primary < 0 {
+		return nil, nil
 	}
 
-	for key, val := range t {
-		if key == string(ext.SpanKind) ||
-			key == string(ext.PeerService) ||
-			key == string(ext.PeerHostIPv4) ||
-			key == string(ext.PeerHostIPv6) ||
-			key == string(ext.PeerPort) {
+	var links []opentracing.SpanReference
+	for i, ref := range refs {
+		sc, ok := ref.ReferencedContext.(SpanContext)
+		if !ok || sc.TraceID.Empty() {
 			continue
 		}
-
-		tags[key] = fmt.Sprint(val)
+		if i != primary {
+			links = append(links, ref)
+		}
 	}
 
-	if len(tags) > 0 {
-		zopts = append(zopts, zipkin.Tags(tags))
+	parent := refs[primary].ReferencedContext.(SpanContext)
+	if refs[primary].Type == opentracing.FollowsFromRef &&
+		t.opts.followsFromOpt == FollowsFromNewTrace {
+		if !parent.TraceID.Empty() {
+			links = append([]opentracing.SpanReference{refs[primary]}, links...)
+		}
+		// start a new trace, only baggage items are carried over as the W3C
+		// tracestate belongs to the referenced trace
+		bag := newBaggage(parent.Baggage).withTraceState("")
+		parent = SpanContext{}
+		if bag != nil {
+			parent.Baggage = bag
+		}
 	}
+	return &parent, links
+}
 
-	if !remoteEndpoint.Empty() {
-		zopts = append(zopts, zipkin.RemoteEndpoint(remoteEndpoint))
+// linkTags returns the tags describing the provided references, e.g.
+// link.0.trace_id, link.0.span_id and link.0.ref_type.
+func linkTags(links []opentracing.SpanReference) map[string]string {
+	tags := make(map[string]string, 3*len(links))
+	for i, link := range links {
+		sc := link.ReferencedContext.(SpanContext)
+		prefix := "link." + strconv.Itoa(i) + "."
+		tags[prefix+"trace_id"] = sc.TraceID.String()
+		tags[prefix+"span_id"] = sc.ID.String()
+		switch link.Type {
+		case opentracing.ChildOfRef:
+			tags[prefix+"ref_type"] = "child_of"
+		case opentracing.FollowsFromRef:
+			tags[prefix+"ref_type"] = "follows_from"
+		default:
+			tags[prefix+"ref_type"] = fmt.Sprint(link.Type)
+		}
 	}
-
-	return zopts
+	return tags
 }
 
 type delegatorType struct{}
@@ -166,6 +267,12 @@ type delegatorType struct{}
 var Delegator delegatorType
 
 func (t *tracerImpl) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	err := t.inject(sc, format, carrier)
+	t.stats.Inject.format(format).record(err)
+	return err
+}
+
+func (t *tracerImpl) inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
 	switch format {
 	case opentracing.TextMap, opentracing.HTTPHeaders:
 		return t.textPropagator.Inject(sc, carrier)
@@ -180,6 +287,16 @@ func (t *tracerImpl) Inject(sc opentracing.SpanContext, format interface{}, carr
 }
 
 func (t *tracerImpl) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	sc, err := t.extract(format, carrier)
+	statsErr := err
+	if zsc, ok := sc.(SpanContext); ok && err == nil && zsc.TraceID.Empty() {
+		statsErr = opentracing.ErrSpanContextNotFound
+	}
+	t.stats.Extract.format(format).record(statsErr)
+	return sc, err
+}
+
+func (t *tracerImpl) extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
 	switch format {
 	case opentracing.TextMap, opentracing.HTTPHeaders:
 		return t.textPropagator.Extract(carrier)
@@ -16,6 +16,9 @@ package zipkintracer
 
 import (
 	otobserver "github.com/opentracing-contrib/go-observer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter"
 )
 
 // B3InjectOption type holds information on B3 injection style when using
@@ -29,10 +32,127 @@ const (
 	B3InjectBoth
 )
 
+// BaggageEncoding type holds information on how baggage items are encoded when
+// using native OpenTracing TextMap and HTTPHeaders carriers.
+type BaggageEncoding int
+
+// Available BaggageEncoding values
+const (
+	// BaggageEncodingOT injects one ot-baggage-<key> header per baggage item.
+	BaggageEncodingOT BaggageEncoding = iota
+	// BaggageEncodingW3C injects all baggage items in a single W3C baggage
+	// header.
+	BaggageEncodingW3C
+	// BaggageEncodingBoth injects both the ot-baggage-<key> headers and the W3C
+	// baggage header.
+	BaggageEncodingBoth
+)
+
+// PropagationFormat type holds information on the trace context headers used
+// with native OpenTracing TextMap and HTTPHeaders carriers. Formats can be
+// combined, e.g. PropagationB3 | PropagationW3C, in which case all of them are
+// injected and the first one found in the order of the constants below is
+// extracted.
+type PropagationFormat int
+
+// Available PropagationFormat values
+const (
+	// PropagationB3 uses the B3 headers, see WithB3InjectOption.
+	PropagationB3 PropagationFormat = 1 << iota
+	// PropagationW3C uses the W3C Trace Context traceparent and tracestate
+	// headers.
+	PropagationW3C
+	// PropagationJaeger uses the Jaeger uber-trace-id header. Baggage items
+	// are injected as uberctx-<key> headers next to the BaggageEncoding
+	// headers.
+	PropagationJaeger
+)
+
+// ExtractOption type holds information on how the trace context is extracted
+// when multiple propagators are configured, see WithPropagator and
+// WithPropagationFormat.
+type ExtractOption int
+
+// Available ExtractOption values
+const (
+	// ExtractFirst uses the trace context of the first propagator finding one.
+	ExtractFirst ExtractOption = iota
+	// ExtractAgreement tries all propagators and rejects the extracted trace
+	// context with opentracing.ErrSpanContextCorrupted if they hold
+	// different trace or span IDs.
+	ExtractAgreement
+)
+
+// FollowsFromOption type holds information on how FollowsFrom references are
+// handled when starting a span.
+type FollowsFromOption int
+
+// Available FollowsFromOption values
+const (
+	// FollowsFromChild starts the span as a child in the trace of the
+	// referenced span.
+	FollowsFromChild FollowsFromOption = iota
+	// FollowsFromNewTrace starts the span as the root of a new trace and
+	// records the referenced span as a link.
+	FollowsFromNewTrace
+)
+
+// ErrorLogOption type holds information on how logs following the
+// OpenTracing error conventions (event=error, error.object, error.kind,
+// message and stack) are recorded. Error logs always result in the Zipkin
+// error tag holding the error message.
+type ErrorLogOption int
+
+// Available ErrorLogOption values
+const (
+	// ErrorLogAnnotations records error logs as annotations.
+	ErrorLogAnnotations ErrorLogOption = iota
+	// ErrorLogTags records the fields of error logs as tags, e.g. error.kind
+	// and stack.
+	ErrorLogTags
+)
+
+// SpanDecorator is called with the operation name and options a span was
+// started with and the underlying zipkin span, e.g. to add derived tags. Tags
+// and annotations added through the span are subject to the Sanitizer and
+// SpanLimits.
+type SpanDecorator func(operationName string, opts opentracing.StartSpanOptions, span zipkin.Span)
+
 // TracerOptions allows creating a customized Tracer.
 type TracerOptions struct {
-	observer    otobserver.Observer
-	b3InjectOpt B3InjectOption
+	observer        otobserver.Observer
+	b3InjectOpt     B3InjectOption
+	baggageEncoding BaggageEncoding
+	baggagePolicy   *BaggagePolicy
+	followsFromOpt  FollowsFromOption
+	errorLogOpt     ErrorLogOption
+	logEncoder      LogEncoder
+	maxLogValueLen  int
+	tagEncoder      TagEncoder
+	spanLimits      *SpanLimits
+	sanitizer       Sanitizer
+
+	operationNameNormalizer OperationNameNormalizer
+	maxOperationNames       int
+	overflowOperationName   string
+
+	defaultTags      opentracing.Tags
+	startDecorators  []SpanDecorator
+	finishDecorators []SpanDecorator
+
+	reporter reporter.Reporter
+
+	tagSampler TagSampler
+
+	leakDetection *LeakDetection
+
+	expvarName string
+
+	logger Logger
+
+	propagationFormat PropagationFormat
+	propagators       []Propagator
+	extractOpt        ExtractOption
 }
 
 // TracerOption allows for functional options.
@@ -52,3 +172,196 @@ func WithB3InjectOption(b3InjectOption B3InjectOption) TracerOption {
 		opts.b3InjectOpt = b3InjectOption
 	}
 }
+
+// WithBaggageEncoding sets the encoding of baggage items if using the native
+// OpenTracing TextMap or HTTPHeaders carriers. Extraction accepts both
+// encodings regardless of this setting.
+func WithBaggageEncoding(encoding BaggageEncoding) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.baggageEncoding = encoding
+	}
+}
+
+// WithBaggagePolicy sets the policy restricting the baggage items accepted by
+// SetBaggageItem, Inject and Extract.
+func WithBaggagePolicy(policy BaggagePolicy) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.baggagePolicy = &policy
+	}
+}
+
+// WithFollowsFromOption sets how spans started with a FollowsFrom reference
+// (and no ChildOf reference) relate to the referenced span.
+func WithFollowsFromOption(followsFromOption FollowsFromOption) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.followsFromOpt = followsFromOption
+	}
+}
+
+// WithErrorLogOption sets how logs following the OpenTracing error
+// conventions are recorded.
+func WithErrorLogOption(errorLogOption ErrorLogOption) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.errorLogOpt = errorLogOption
+	}
+}
+
+// WithLogEncoder sets the encoder turning the fields of a log call into a
+// single annotation, e.g. KeyValueLogEncoder or JSONLogEncoder. By default
+// each log field results in its own annotation.
+func WithLogEncoder(encoder LogEncoder) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.logEncoder = encoder
+	}
+}
+
+// WithMaxLogValueLength truncates log field values longer than maxLength
+// bytes.
+func WithMaxLogValueLength(maxLength int) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.maxLogValueLen = maxLength
+	}
+}
+
+// WithTagEncoder sets the encoder of tag values. DefaultTagEncoder is used if
+// not set.
+func WithTagEncoder(encoder TagEncoder) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.tagEncoder = encoder
+	}
+}
+
+// WithSpanLimits caps the number and size of tags and annotations recorded on
+// each span.
+func WithSpanLimits(limits SpanLimits) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.spanLimits = &limits
+	}
+}
+
+// WithSanitizer sets the Sanitizer applied to tags, log fields and operation
+// names before they are recorded, e.g. NewRedactor("password").
+func WithSanitizer(sanitizer Sanitizer) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.sanitizer = sanitizer
+	}
+}
+
+// WithOperationNameNormalizer sets the normalizer applied to operation names
+// before they are recorded, e.g. DefaultOperationNameNormalizer.
+func WithOperationNameNormalizer(normalizer OperationNameNormalizer) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.operationNameNormalizer = normalizer
+	}
+}
+
+// WithMaxOperationNames caps the number of distinct (normalized) operation
+// names recorded by the tracer. Once maxNames distinct names are recorded,
+// spans with a new name are named overflowName and hold their name in the
+// OriginalOperationNameKey tag.
+func WithMaxOperationNames(maxNames int, overflowName string) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.maxOperationNames = maxNames
+		opts.overflowOperationName = overflowName
+	}
+}
+
+// WithDefaultTags sets tags on every span, e.g. the deployment environment or
+// version. Tags provided when starting a span take precedence.
+func WithDefaultTags(tags opentracing.Tags) TracerOption {
+	return func(opts *TracerOptions) {
+		if opts.defaultTags == nil {
+			opts.defaultTags = make(opentracing.Tags, len(tags))
+		}
+		for k, v := range tags {
+			opts.defaultTags[k] = v
+		}
+	}
+}
+
+// WithStartDecorator adds a decorator called when a span is started.
+func WithStartDecorator(decorator SpanDecorator) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.startDecorators = append(opts.startDecorators, decorator)
+	}
+}
+
+// WithFinishDecorator adds a decorator called when a span is finished, before
+// it is reported. The operation name is the latest one set on the span.
+func WithFinishDecorator(decorator SpanDecorator) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.finishDecorators = append(opts.finishDecorators, decorator)
+	}
+}
+
+// WithReporter sets the reporter of the wrapped zipkin tracer, which is closed
+// when the tracer is flushed or closed.
+func WithReporter(r reporter.Reporter) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.reporter = r
+	}
+}
+
+// WithTagSampler sets the sampler deciding whether new traces are sampled
+// based on the operation name and tags of their root span. Sampling decisions
+// of a parent or the sampling.priority tag take precedence.
+func WithTagSampler(sampler TagSampler) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.tagSampler = sampler
+	}
+}
+
+// WithLeakDetection reports spans which are not finished within the
+// configured threshold. The detection stops when the tracer is closed.
+func WithLeakDetection(detection LeakDetection) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.leakDetection = &detection
+	}
+}
+
+// WithExpvar publishes the Stats of the tracer through expvar under the
+// provided name. As expvar.Publish, it panics if the name is already in use.
+func WithExpvar(name string) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.expvarName = name
+	}
+}
+
+// WithLogger sets the logger reporting errors the tracer can not return to
+// the caller, e.g. invalid LogKV arguments or carrier errors on Extract, and
+// data it drops or coerces. At most DefaultLogRate messages are reported per
+// second.
+func WithLogger(logger Logger) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.logger = logger
+	}
+}
+
+// WithPropagationFormat sets the trace context headers used with the native
+// OpenTracing TextMap and HTTPHeaders carriers. It defaults to PropagationB3.
+func WithPropagationFormat(format PropagationFormat) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.propagationFormat = format
+	}
+}
+
+// WithPropagator adds a propagator of the trace context headers used with the
+// native OpenTracing TextMap and HTTPHeaders carriers. All propagators are
+// injected and extracted in the order they were added, see WithExtractOption.
+// Added propagators replace the formats set by WithPropagationFormat, e.g.
+//
+//	WithPropagator(NewB3Propagator(B3InjectSingle)),
+//	WithPropagator(NewW3CPropagator()),
+func WithPropagator(propagator Propagator) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.propagators = append(opts.propagators, propagator)
+	}
+}
+
+// WithExtractOption sets how the trace context is extracted when multiple
+// propagators are configured. It defaults to ExtractFirst.
+func WithExtractOption(extractOption ExtractOption) TracerOption {
+	return func(opts *TracerOptions) {
+		opts.extractOpt = extractOption
+	}
+}
@@ -15,6 +15,7 @@
 package zipkintracer
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/openzipkin/zipkin-go/model"
@@ -38,7 +39,7 @@ func TestOTKindTagIsParsedSuccessfuly(t *testing.T) {
 		{"span.kind": ext.SpanKindRPCServerEnum},
 	}
 	for _, tags := range tagCases {
-		opts := parseTagsAsZipkinOptions(tags)
+		opts := zipkinOptions(parseTags(tags, DefaultTagEncoder, nil))
 
 		rec := recorder.NewReporter()
 		tr, _ := zipkin.NewTracer(rec)
@@ -57,7 +58,7 @@ func TestOTKindTagIsParsedSuccessfuly(t *testing.T) {
 
 func TestOTKindTagIsCantBeParsed(t *testing.T) {
 	tags := map[string]interface{}{"span.kind": "banana"}
-	opts := parseTagsAsZipkinOptions(tags)
+	opts := zipkinOptions(parseTags(tags, DefaultTagEncoder, nil))
 
 	rec := recorder.NewReporter()
 	tr, _ := zipkin.NewTracer(rec)
@@ -81,7 +82,7 @@ func TestOptionsFromOTTags(t *testing.T) {
 	tags := map[string]interface{}{}
 	tags[string(ext.PeerService)] = "service_a"
 	tags["key"] = "value"
-	opts := parseTagsAsZipkinOptions(tags)
+	opts := zipkinOptions(parseTags(tags, DefaultTagEncoder, nil))
 
 	rec := recorder.NewReporter()
 	tr, _ := zipkin.NewTracer(rec)
@@ -100,3 +101,159 @@ func TestOptionsFromOTTags(t *testing.T) {
 		t.Errorf("unexpected tag value, want %s, have %s", want, have)
 	}
 }
+
+func TestStartSpanWithReferences(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr)
+
+	producer1 := tracer.StartSpan("producer1")
+	producer2 := tracer.StartSpan("producer2")
+	producer2.SetBaggageItem("key", "value")
+	parent := tracer.StartSpan("parent")
+
+	sp := tracer.StartSpan(
+		"consumer",
+		opentracing.FollowsFrom(producer1.Context()),
+		opentracing.ChildOf(parent.Context()),
+		opentracing.FollowsFrom(producer2.Context()),
+	)
+	sp.Finish()
+
+	spans := rec.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected number of spans, want %d, have %d", want, have)
+	}
+
+	parentSC := parent.Context().(SpanContext)
+	if want, have := parentSC.TraceID, spans[0].TraceID; want != have {
+		t.Errorf("unexpected trace id, want %s, have %s", want, have)
+	}
+	if want, have := parentSC.ID, *spans[0].ParentID; want != have {
+		t.Errorf("unexpected parent id, want %s, have %s", want, have)
+	}
+
+	p1 := producer1.Context().(SpanContext)
+	p2 := producer2.Context().(SpanContext)
+	wantTags := map[string]string{
+		"link.0.trace_id": p1.TraceID.String(),
+		"link.0.span_id":  p1.ID.String(),
+		"link.0.ref_type": "follows_from",
+		"link.1.trace_id": p2.TraceID.String(),
+		"link.1.span_id":  p2.ID.String(),
+		"link.1.ref_type": "follows_from",
+	}
+	if want, have := wantTags, spans[0].Tags; !reflect.DeepEqual(want, have) {
+		t.Errorf("unexpected tags, want %+v, have %+v", want, have)
+	}
+
+	if want, have := "value", sp.BaggageItem("key"); want != have {
+		t.Errorf("unexpected baggage item, want %s, have %s", want, have)
+	}
+}
+
+func TestStartSpanFollowsFrom(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+
+	for _, opt := range []FollowsFromOption{FollowsFromChild, FollowsFromNewTrace} {
+		tracer := Wrap(tr, WithFollowsFromOption(opt))
+		producer := tracer.StartSpan("producer")
+		producerSC := producer.Context().(SpanContext)
+
+		sp := tracer.StartSpan("consumer", opentracing.FollowsFrom(producerSC))
+		sp.Finish()
+
+		spans := rec.Flush()
+		if want, have := 1, len(spans); want != have {
+			t.Fatalf("unexpected number of spans, want %d, have %d", want, have)
+		}
+
+		if opt == FollowsFromChild {
+			if want, have := producerSC.TraceID, spans[0].TraceID; want != have {
+				t.Errorf("unexpected trace id, want %s, have %s", want, have)
+			}
+			if want, have := 0, len(spans[0].Tags); want != have {
+				t.Errorf("unexpected number of tags, want %d, have %d", want, have)
+			}
+			continue
+		}
+
+		if spans[0].TraceID == producerSC.TraceID || spans[0].ParentID != nil {
+			t.Errorf("expected new root span, have %+v", spans[0].SpanContext)
+		}
+		if want, have := producerSC.TraceID.String(), spans[0].Tags["link.0.trace_id"]; want != have {
+			t.Errorf("unexpected link trace id, want %s, have %s", want, have)
+		}
+		if want, have := producerSC.ID.String(), spans[0].Tags["link.0.span_id"]; want != have {
+			t.Errorf("unexpected link span id, want %s, have %s", want, have)
+		}
+	}
+}
+
+func TestDefaultTagsAndDecorators(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr,
+		WithDefaultTags(opentracing.Tags{"env": "prod", "version": "1.0"}),
+		WithDefaultTags(opentracing.Tags{"region": "eu-west-1"}),
+		WithStartDecorator(func(operationName string, opts opentracing.StartSpanOptions, span zipkin.Span) {
+			span.Tag("start.name", operationName)
+			span.Tag("start.env", opts.Tags["env"].(string))
+		}),
+		WithFinishDecorator(func(operationName string, _ opentracing.StartSpanOptions, span zipkin.Span) {
+			span.Tag("finish.name", operationName)
+		}),
+	)
+
+	sp := tracer.StartSpan("get", opentracing.Tag{Key: "version", Value: "2.0"})
+	sp.SetOperationName("get_user")
+	sp.Finish()
+
+	spans := rec.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected number of spans, want %d, have %d", want, have)
+	}
+	wantTags := map[string]string{
+		"env":         "prod",
+		"version":     "2.0",
+		"region":      "eu-west-1",
+		"start.name":  "get",
+		"start.env":   "prod",
+		"finish.name": "get_user",
+	}
+	if want, have := wantTags, spans[0].Tags; !reflect.DeepEqual(want, have) {
+		t.Errorf("unexpected tags, want %v, have %v", want, have)
+	}
+}
+
+func TestDecoratorsSanitizedAndLimited(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr,
+		WithSanitizer(NewRedactor("secret")),
+		WithSpanLimits(SpanLimits{MaxTags: 2}),
+		WithStartDecorator(func(_ string, _ opentracing.StartSpanOptions, span zipkin.Span) {
+			span.Tag("secret", "s3cr3t")
+			span.Tag("user", "alice@example.com")
+		}),
+		WithFinishDecorator(func(_ string, _ opentracing.StartSpanOptions, span zipkin.Span) {
+			span.Tag("dropped", "value")
+		}),
+	)
+
+	tracer.StartSpan("x").Finish()
+
+	spans := rec.Flush()
+	if want, have := 1, len(spans); want != have {
+		t.Fatalf("unexpected number of spans, want %d, have %d", want, have)
+	}
+	wantTags := map[string]string{
+		"secret":       RedactedMask,
+		"user":         RedactedMask,
+		DroppedTagsKey: "1",
+	}
+	if want, have := wantTags, spans[0].Tags; !reflect.DeepEqual(want, have) {
+		t.Errorf("unexpected tags, want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// W3C Trace Context header keys, see https://www.w3.org/TR/trace-context/
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+)
+
+const (
+	w3cVersion      = "00"
+	w3cSampledFlag  = 0x01
+	w3cInvalidTrace = "00000000000000000000000000000000"
+	w3cInvalidSpan  = "0000000000000000"
+)
+
+// NewW3CPropagator returns the Propagator of the W3C Trace Context
+// traceparent and tracestate headers. The tracestate of an extracted context
+// is forwarded by its child spans.
+func NewW3CPropagator() Propagator {
+	return w3cPropagator{}
+}
+
+type w3cPropagator struct{}
+
+func (w3cPropagator) Inject(sc model.SpanContext, set func(key, value string)) error {
+	return injectW3C(sc, newBaggage(sc.Baggage).getTraceState(), set)
+}
+
+// Extract returns the SpanContext of the traceparent header. Its baggage
+// holds the tracestate, which is only valid along with a valid traceparent
+// and can be split across multiple headers.
+func (w3cPropagator) Extract(headers Headers) (*model.SpanContext, error) {
+	traceparent := strings.TrimSpace(headers.Get(traceparentHeader))
+	if traceparent == "" {
+		return nil, nil
+	}
+	sc, err := parseTraceparent(traceparent)
+	if err != nil {
+		return nil, err
+	}
+	var members []string
+	for _, value := range headers.Values(tracestateHeader) {
+		if value = strings.TrimSpace(value); value != "" {
+			members = append(members, value)
+		}
+	}
+	if len(members) > 0 {
+		sc.Baggage = &baggage{traceState: strings.Join(members, ",")}
+	}
+	return sc, nil
+}
+
+// parseTraceparent parses a traceparent header of the form
+// {version}-{trace-id}-{parent-id}-{trace-flags}. Fields following the
+// flags are accepted for future versions.
+func parseTraceparent(header string) (*model.SpanContext, error) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	if !validTraceparent(parts) {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	traceID, spanID, flags := parts[1], parts[2], parts[3]
+
+	tid, err := model.TraceIDFromHex(traceID)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	id, err := strconv.ParseUint(spanID, 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	f, err := strconv.ParseUint(flags, 16, 8)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	sampled := f&w3cSampledFlag == w3cSampledFlag
+	return &model.SpanContext{
+		TraceID: tid,
+		ID:      model.ID(id),
+		Sampled: &sampled,
+	}, nil
+}
+
+// validTraceparent reports whether the traceparent fields have the expected
+// length and hold valid lowercase hex values.
+func validTraceparent(parts []string) bool {
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" ||
+		(version == w3cVersion && len(parts) != 4) {
+		return false
+	}
+	return len(traceID) == 32 && isLowerHex(traceID) && traceID != w3cInvalidTrace &&
+		len(spanID) == 16 && isLowerHex(spanID) && spanID != w3cInvalidSpan &&
+		len(flags) == 2 && isLowerHex(flags)
+}
+
+// injectW3C writes the traceparent and tracestate headers. 64-bit trace IDs
+// are left padded with zeros. Debug spans are flagged as sampled.
+func injectW3C(sc model.SpanContext, tracestate string, set func(key, value string)) error {
+	if sc.TraceID.Empty() || sc.ID == 0 {
+		return opentracing.ErrInvalidSpanContext
+	}
+	var flags byte
+	if sc.Debug || (sc.Sampled != nil && *sc.Sampled) {
+		flags |= w3cSampledFlag
+	}
+	set(traceparentHeader, fmt.Sprintf("%s-%016x%016x-%016x-%02x",
+		w3cVersion, sc.TraceID.High, sc.TraceID.Low, uint64(sc.ID), flags))
+	if tracestate != "" {
+		set(tracestateHeader, tracestate)
+	}
+	return nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sampled, unsampled := true, false
+	testCases := []struct {
+		header   string
+		expected *model.SpanContext
+	}{
+		{
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			&model.SpanContext{
+				TraceID: model.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736},
+				ID:      0x00f067aa0ba902b7,
+				Sampled: &sampled,
+			},
+		},
+		{
+			"00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			&model.SpanContext{
+				TraceID: model.TraceID{Low: 0xa3ce929d0e0e4736},
+				ID:      0x00f067aa0ba902b7,
+				Sampled: &unsampled,
+			},
+		},
+		// future versions can hold additional fields
+		{
+			"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+			&model.SpanContext{
+				TraceID: model.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736},
+				ID:      0x00f067aa0ba902b7,
+				Sampled: &sampled,
+			},
+		},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", nil},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", nil},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", nil},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", nil},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", nil},
+		{"00-4bf92f3577b34da6-00f067aa0ba902b7-01", nil},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", nil},
+	}
+	for _, tc := range testCases {
+		sc, err := parseTraceparent(tc.header)
+		if tc.expected == nil {
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, tc.header)
+			continue
+		}
+		assert.NoError(t, err, tc.header)
+		assert.Equal(t, tc.expected, sc, tc.header)
+	}
+}
+
+func TestW3CPropagation(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithPropagationFormat(PropagationW3C))
+
+	carrier := opentracing.HTTPHeadersCarrier(http.Header{
+		"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"Tracestate":  {"congo=t61rcWkgMzE", "rojo=00f067aa0ba902b7"},
+	})
+	parent, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.NoError(t, err)
+
+	span := tracer.StartSpan("x", opentracing.ChildOf(parent))
+	textMap := opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, textMap))
+
+	sc := span.Context().(SpanContext)
+	assert.Equal(t, opentracing.TextMapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-" + sc.ID.String() + "-01",
+		"tracestate":  "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7",
+	}, textMap)
+	span.Finish()
+
+	// 64-bit trace IDs are padded
+	root := tracer.StartSpan("root")
+	textMap = opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(root.Context(), opentracing.TextMap, textMap))
+	rootSC := root.Context().(SpanContext)
+	assert.Equal(t, "00-0000000000000000"+rootSC.TraceID.String()+"-"+rootSC.ID.String()+"-01", textMap["traceparent"])
+
+	extracted, err := tracer.Extract(opentracing.TextMap, textMap)
+	assert.NoError(t, err)
+	assert.Equal(t, rootSC.TraceID, extracted.(SpanContext).TraceID)
+	assert.Equal(t, rootSC.ID, extracted.(SpanContext).ID)
+}
+
+func TestB3AndW3CPropagation(t *testing.T) {
+	tracer := Wrap(mustTracer(t), WithPropagationFormat(PropagationB3|PropagationW3C))
+
+	span := tracer.StartSpan("x")
+	carrier := opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, carrier))
+	assert.Contains(t, carrier, "x-b3-traceid")
+	assert.Contains(t, carrier, "traceparent")
+
+	// B3 is preferred, W3C is used if B3 headers are invalid
+	carrier["traceparent"] = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := tracer.Extract(opentracing.TextMap, carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, span.Context().(SpanContext).TraceID, sc.(SpanContext).TraceID)
+
+	carrier["x-b3-traceid"] = "invalid"
+	sc, err = tracer.Extract(opentracing.TextMap, carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.(SpanContext).TraceID.String())
+
+	delete(carrier, "traceparent")
+	_, err = tracer.Extract(opentracing.TextMap, carrier)
+	assert.Error(t, err)
+}
+
+func TestW3CTraceStateNotCarriedToNewTrace(t *testing.T) {
+	tracer := Wrap(mustTracer(t),
+		WithPropagationFormat(PropagationW3C),
+		WithFollowsFromOption(FollowsFromNewTrace),
+	)
+
+	parent, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		"traceparent":     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"tracestate":      "congo=t61rcWkgMzE",
+		"ot-baggage-user": "alice",
+	})
+	assert.NoError(t, err)
+
+	span := tracer.StartSpan("consumer", opentracing.FollowsFrom(parent))
+	carrier := opentracing.TextMapCarrier{}
+	assert.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, carrier))
+	assert.NotContains(t, carrier, "tracestate")
+	assert.Equal(t, "alice", carrier["ot-baggage-user"])
+	assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.Context().(SpanContext).TraceID.String())
+	span.Finish()
+}
+
+func mustTracer(t *testing.T) *zipkin.Tracer {
+	tr, err := zipkin.NewTracer(recorder.NewReporter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"reflect"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+)
+
+// ZipkinSpan returns the zipkin span backing a span created by a tracer
+// returned by Wrap. Spans started by the tracer are reported when the
+// OpenTracing span is finished, finishing the returned zipkin span does not
+// report them. The context of the returned span carries the sampling decision
+// forced by a sampling.priority tag, which zipkin-go children follow.
+func ZipkinSpan(span opentracing.Span) (zipkin.Span, bool) {
+	sp, ok := span.(*spanImpl)
+	if !ok {
+		return nil, false
+	}
+	return bridgedSpan{sp.zipkinSpan, sp}, true
+}
+
+// bridgedSpan is the zipkin span returned by ZipkinSpan.
+type bridgedSpan struct {
+	zipkin.Span
+	span *spanImpl
+}
+
+func (b bridgedSpan) Context() model.SpanContext {
+	return b.span.withForcedSampling(b.Span.Context())
+}
+
+// FromZipkinSpan wraps a span created by a zipkin-go tracer as an OpenTracing
+// span of a tracer returned by Wrap. The span is subject to the observer and
+// options of the tracer, except for those applied on span start, and must be
+// finished through either the returned span or the zipkin span but not both.
+// Flush and Close do not wait for it and it is not subject to leak detection.
+// As zipkin-go reports sampled spans when they are finished, the returned
+// span is not flushed on finish unless its sampling is forced by a
+// sampling.priority tag; spans created with zipkin.FlushOnFinish(false) must
+// be flushed by the caller. A noop span is returned if the tracer was not
+// returned by Wrap, the span returned by ZipkinSpan is unwrapped.
+func FromZipkinSpan(tracer opentracing.Tracer, span zipkin.Span) opentracing.Span {
+	t, ok := tracer.(*tracerImpl)
+	if !ok {
+		return opentracing.NoopTracer{}.StartSpan("")
+	}
+	if b, ok := span.(bridgedSpan); ok && b.span.tracer == t {
+		return b.span
+	}
+
+	sc := span.Context()
+	sp := &spanImpl{
+		zipkinSpan: span,
+		tracer:     t,
+		startTime:  time.Now(),
+		baggage:    newBaggage(sc.Baggage),
+		root:       sc.ParentID == nil,
+		limiter:    newSpanLimiter(t.opts.spanLimits),
+
+		selfReporting: true,
+	}
+	if m := spanModel(span); m != nil {
+		sp.startTime = m.Timestamp
+		sp.kind = m.Kind
+		sp.operationName = m.Name
+		if m.RemoteEndpoint != nil {
+			sp.remoteEndpoint = *m.RemoteEndpoint
+		}
+	}
+	sp.startOptions.StartTime = sp.startTime
+
+	t.track(sp)
+	if t.opts.observer != nil {
+		t.stats.ObserverCallbacks.inc()
+		observer, _ := t.opts.observer.OnStartSpan(sp, sp.operationName, sp.startOptions)
+		sp.observer = observer
+	}
+	return sp
+}
+
+// zipkinSpanImpl returns the struct backing spans created by the zipkin-go
+// tracer. The returned Value is invalid for noop spans.
+func zipkinSpanImpl(sp zipkin.Span) reflect.Value {
+	v := reflect.ValueOf(sp)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
+// spanModel returns the model backing spans created by the zipkin-go tracer,
+// allowing to update properties which have no setter in the zipkin.Span
+// interface. It returns nil for noop spans.
+func spanModel(sp zipkin.Span) *model.SpanModel {
+	v := zipkinSpanImpl(sp)
+	if !v.IsValid() {
+		return nil
+	}
+	f := v.FieldByName("SpanModel")
+	if !f.IsValid() || !f.CanAddr() {
+		return nil
+	}
+	m, _ := f.Addr().Interface().(*model.SpanModel)
+	return m
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 The OpenZipkin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkintracer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZipkinSpan(t *testing.T) {
+	rec := recorder.NewReporter()
+	tracer := newTracer(rec)
+
+	span := tracer.StartSpan("x")
+	zs, ok := ZipkinSpan(span)
+	assert.True(t, ok)
+	assert.Equal(t, model.SpanContext(span.Context().(SpanContext)), zs.Context())
+	assert.Equal(t, span, FromZipkinSpan(tracer, zs))
+
+	zs.Tag("native", "true")
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "true", spans[0].Tags["native"])
+
+	_, ok = ZipkinSpan(opentracing.NoopTracer{}.StartSpan("x"))
+	assert.False(t, ok)
+}
+
+func TestFromZipkinSpan(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr)
+
+	zs := tr.StartSpan("native", zipkin.Kind(model.Server))
+	span := FromZipkinSpan(tracer, zs)
+	span.SetTag("bridged", true)
+	child := tracer.StartSpan("child", opentracing.ChildOf(span.Context()))
+	child.Finish()
+	span.Finish()
+	span.Finish()
+
+	spans := rec.Flush()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, zs.Context().ID, *spans[0].ParentID)
+	assert.Equal(t, "native", spans[1].Name)
+	assert.Equal(t, model.Server, spans[1].Kind)
+	assert.Equal(t, "true", spans[1].Tags["bridged"])
+
+	// unsampled native spans are reported once sampling is forced
+	unsampled, _ := zipkin.NewTracer(rec, zipkin.WithSampler(zipkin.NeverSample))
+	start := time.Now().Add(-time.Minute)
+	span = FromZipkinSpan(Wrap(unsampled, WithReporter(rec)), unsampled.StartSpan("forced", zipkin.StartTime(start)))
+	span.SetTag(string(ext.SamplingPriority), 1)
+	span.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(5 * time.Second)})
+
+	spans = rec.Flush()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "forced", spans[0].Name)
+	assert.Equal(t, 5*time.Second, spans[0].Duration)
+
+	// sampled native spans are not reported once sampling is suppressed
+	span = FromZipkinSpan(tracer, tr.StartSpan("suppressed"))
+	span.SetTag(string(ext.SamplingPriority), 0)
+	span.Finish()
+	assert.Equal(t, 0, len(rec.Flush()))
+
+	span = FromZipkinSpan(opentracing.NoopTracer{}, zs)
+	assert.Equal(t, opentracing.NoopTracer{}, span.Tracer())
+}
+
+func TestFromZipkinSpanFinishedNatively(t *testing.T) {
+	rec := recorder.NewReporter()
+	tr, _ := zipkin.NewTracer(rec)
+	tracer := Wrap(tr, WithReporter(rec), WithLeakDetection(LeakDetection{}))
+
+	zs := tr.StartSpan("native")
+	FromZipkinSpan(tracer, zs)
+	zs.Finish()
+	assert.Equal(t, 1, len(rec.Flush()))
+	assert.True(t, AssertNoLeakedSpans(t, tracer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, tracer.(interface{ Flush(context.Context) error }).Flush(ctx))
+}